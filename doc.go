@@ -1,5 +1,6 @@
 // Package mmap provides higher level abstractions around a memory
-// mapped file. For now, package only support creating mmap with
-// backed file on disk (shared mappings). Anonymous mappings are currently
-// not supported. Please see godoc for various function references.
+// mapped file. Package supports creating mmap with backed file on disk,
+// either as shared mappings or private (copy-on-write) mappings, as well
+// as anonymous mappings that are not backed by any file. Please see godoc
+// for various function references.
 package mmap
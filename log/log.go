@@ -0,0 +1,253 @@
+// Package log implements a persistent append-only log of records stored in
+// a memory mapped file. Every record is framed with its length and CRC32C
+// checksum, the file grows automatically as records are appended and a torn
+// tail left behind by a crash is truncated when the log is opened again.
+package log
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"math"
+	"os"
+	"sync"
+	"sync/atomic"
+	"syscall"
+
+	"github.com/grandecola/mmap"
+)
+
+// Layout of the file: a header of headerSize bytes containing magic, version
+// and the write cursor, followed by the records. A record is a little endian
+// uint32 length and uint32 CRC32C of the length and the data, followed by the
+// data. The length is checksummed so that zeroed space is never a valid record.
+const (
+	magic   = 0x474f4c4d // "MLOG"
+	version = 1
+
+	cursorOffset     = 8
+	headerSize       = 16
+	recordHeaderSize = 8
+	initialSize      = 64 << 10
+)
+
+// First is the offset of the first record in a log.
+const First int64 = headerSize
+
+var (
+	// ErrCorrupted is returned when the file is not a log or a record fails the checksum.
+	ErrCorrupted = errors.New("log corrupted")
+	// ErrInvalidOffset is returned when no record starts at the given offset.
+	ErrInvalidOffset = errors.New("invalid record offset")
+	// ErrRecordTooLarge is returned when appending a record larger than math.MaxUint32 bytes.
+	ErrRecordTooLarge = errors.New("record too large")
+)
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// Log is an append-only log of records. Log is safe for concurrent use,
+// records can be read while other records are being appended.
+type Log struct {
+	mu  sync.Mutex
+	m   *mmap.File
+	end atomic.Int64
+}
+
+// Open opens the log stored in the named file, creating the file if it does
+// not exist. A torn tail, starting from the first record that fails the
+// checksum, is truncated. Options are passed to mmap.Open, the growth policy and
+// concurrent access are set by Open. The log must not be opened more than
+// once at a time.
+func Open(path string, opts ...mmap.Option) (*Log, error) {
+	opts = append(opts[:len(opts):len(opts)],
+		mmap.WithGrowthPolicy(mmap.GrowDoubling()), mmap.WithConcurrentAccess())
+
+	info, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) || err == nil && info.Size() == 0 {
+		return create(path, opts)
+	} else if err != nil {
+		return nil, err
+	}
+
+	m, err := mmap.Open(path, os.O_RDWR, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	l := &Log{m: m}
+	if err := l.repair(); err != nil {
+		_ = m.Close()
+		return nil, err
+	}
+
+	return l, nil
+}
+
+func create(path string, opts []mmap.Option) (*Log, error) {
+	m, err := mmap.Create(path, initialSize, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	l := &Log{m: m}
+	if err := l.format(); err != nil {
+		_ = m.Close()
+		return nil, err
+	}
+
+	return l, nil
+}
+
+// format writes the header of an empty log.
+func (l *Log) format() error {
+	if err := l.m.TryWriteUint64At(magic|version<<32, 0); err != nil {
+		return err
+	}
+	if err := l.m.TryWriteUint64At(headerSize, cursorOffset); err != nil {
+		return err
+	}
+
+	l.end.Store(headerSize)
+	return l.m.Flush(syscall.MS_SYNC)
+}
+
+// repair validates the header and truncates the records
+// after the first record that fails the checksum.
+func (l *Log) repair() error {
+	header, err := l.m.TryReadUint64At(0)
+	if err != nil || header != magic|version<<32 {
+		return fmt.Errorf("%w: invalid header", ErrCorrupted)
+	}
+	cursor, err := l.m.TryReadUint64At(cursorOffset)
+	if err != nil || cursor < headerSize {
+		return fmt.Errorf("%w: invalid write cursor", ErrCorrupted)
+	}
+
+	end := min(int64(cursor), l.m.ValidLength())
+	offset := int64(headerSize)
+	for offset < end {
+		_, next, err := l.read(offset, end)
+		if err != nil {
+			break
+		}
+		offset = next
+	}
+
+	l.end.Store(offset)
+	if offset == int64(cursor) {
+		return nil
+	}
+	if err := l.m.TryWriteUint64At(uint64(offset), cursorOffset); err != nil {
+		return err
+	}
+	return l.m.Flush(syscall.MS_SYNC)
+}
+
+// Append appends record to the log and returns the offset of the record.
+// Appended records are persisted on disk by calling Sync.
+func (l *Log) Append(record []byte) (int64, error) {
+	if uint64(len(record)) > math.MaxUint32 {
+		return 0, ErrRecordTooLarge
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	offset := l.end.Load()
+	header := uint64(len(record)) | uint64(checksum(record))<<32
+	if err := l.m.TryWriteUint64At(header, offset); err != nil {
+		return 0, err
+	}
+	if len(record) > 0 {
+		if _, err := l.m.TryWriteAt(record, offset+recordHeaderSize); err != nil {
+			return 0, err
+		}
+	}
+
+	end := offset + recordHeaderSize + int64(len(record))
+	if err := l.m.TryWriteUint64At(uint64(end), cursorOffset); err != nil {
+		return 0, err
+	}
+
+	l.end.Store(end)
+	return offset, nil
+}
+
+// Read returns a copy of the record at offset along with
+// the offset of the next record.
+func (l *Log) Read(offset int64) ([]byte, int64, error) {
+	return l.read(offset, l.end.Load())
+}
+
+// Iterate calls fn for each record starting from the record at offset, in
+// the order the records were appended, including the records appended while
+// iterating. Iterate stops and returns the error if fn returns an error.
+// Use First as offset to iterate over all the records.
+func (l *Log) Iterate(offset int64, fn func(offset int64, record []byte) error) error {
+	for offset < l.end.Load() {
+		record, next, err := l.Read(offset)
+		if err != nil {
+			return err
+		}
+		if err := fn(offset, record); err != nil {
+			return err
+		}
+		offset = next
+	}
+
+	return nil
+}
+
+// Size returns the size of the log in bytes, which
+// is also the offset of the next appended record.
+func (l *Log) Size() int64 {
+	return l.end.Load()
+}
+
+// Sync flushes the appended records to disk.
+func (l *Log) Sync() error {
+	return l.m.Flush(syscall.MS_SYNC)
+}
+
+// Close syncs and closes the log.
+func (l *Log) Close() error {
+	return l.m.Close()
+}
+
+// read returns a copy of the record at offset along with the
+// offset of the next record, if the record ends before end.
+func (l *Log) read(offset, end int64) ([]byte, int64, error) {
+	if offset < headerSize || offset+recordHeaderSize > end {
+		return nil, 0, fmt.Errorf("%w: %d", ErrInvalidOffset, offset)
+	}
+
+	header, err := l.m.TryReadUint64At(offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	length := int64(uint32(header))
+	next := offset + recordHeaderSize + length
+	if next > end {
+		return nil, 0, fmt.Errorf("%w: %d", ErrInvalidOffset, offset)
+	}
+
+	record := make([]byte, length)
+	if length > 0 {
+		if _, err := l.m.TryReadAt(record, offset+recordHeaderSize); err != nil {
+			return nil, 0, err
+		}
+	}
+	if checksum(record) != uint32(header>>32) {
+		return nil, 0, fmt.Errorf("%w: checksum mismatch at offset %d", ErrCorrupted, offset)
+	}
+
+	return record, next, nil
+}
+
+// checksum returns CRC32C of the little endian uint32 length of the record followed by the record.
+func checksum(record []byte) uint32 {
+	var length [4]byte
+	binary.LittleEndian.PutUint32(length[:], uint32(len(record)))
+	return crc32.Update(crc32.Checksum(length[:], castagnoli), castagnoli, record)
+}
@@ -0,0 +1,196 @@
+package log
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"os"
+	"path"
+	"sync"
+	"testing"
+)
+
+func records() [][]byte {
+	rs := [][]byte{[]byte("0123456789"), {}, []byte("ABCDEFGHIJKLMNOPQRSTUVWXYZ")}
+	return append(rs, bytes.Repeat([]byte("abc"), initialSize))
+}
+
+func TestLog(t *testing.T) {
+	t.Parallel()
+
+	testPath := path.Join(t.TempDir(), "m.log")
+	l, err := Open(testPath)
+	if err != nil {
+		t.Fatalf("error in opening log :: %v", err)
+	}
+	if l.Size() != First {
+		t.Fatalf("size of empty log %d, expected %d", l.Size(), First)
+	}
+
+	var offsets []int64
+	for _, r := range records() {
+		offset, err := l.Append(r)
+		if err != nil {
+			t.Fatalf("error in appending record :: %v", err)
+		}
+		offsets = append(offsets, offset)
+	}
+
+	record, next, err := l.Read(offsets[2])
+	if err != nil || !bytes.Equal(record, records()[2]) || next != offsets[3] {
+		t.Fatalf("error in reading record :: %v", err)
+	}
+	if _, _, err := l.Read(offsets[2] + 1); !errors.Is(err, ErrInvalidOffset) && !errors.Is(err, ErrCorrupted) {
+		t.Fatalf("different error than expected in Read :: %v", err)
+	}
+	if _, _, err := l.Read(l.Size()); !errors.Is(err, ErrInvalidOffset) {
+		t.Fatalf("different error than expected in Read :: %v", err)
+	}
+
+	size := l.Size()
+	if err := l.Close(); err != nil {
+		t.Fatalf("error in closing log :: %v", err)
+	}
+
+	// Reopen and iterate over all the records
+	l, err = Open(testPath)
+	if err != nil {
+		t.Fatalf("error in opening log :: %v", err)
+	}
+	if l.Size() != size {
+		t.Fatalf("size of log %d, expected %d", l.Size(), size)
+	}
+
+	var i int
+	if err := l.Iterate(First, func(offset int64, record []byte) error {
+		if offset != offsets[i] || !bytes.Equal(record, records()[i]) {
+			t.Fatalf("record %d at offset %d is not equal to record appended", i, offset)
+		}
+		i++
+		return nil
+	}); err != nil || i != len(offsets) {
+		t.Fatalf("error in iterating over %d records :: %v", i, err)
+	}
+
+	errStop := errors.New("stop")
+	if err := l.Iterate(offsets[1], func(offset int64, record []byte) error {
+		return errStop
+	}); err != errStop {
+		t.Fatalf("different error than expected in Iterate :: %v", err)
+	}
+
+	// Readers iterate while records are being appended
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for range 100 {
+			if _, err := l.Append([]byte("concurrent")); err != nil {
+				t.Errorf("error in appending record :: %v", err)
+			}
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for range 10 {
+			if err := l.Iterate(First, func(int64, []byte) error { return nil }); err != nil {
+				t.Errorf("error in iterating :: %v", err)
+			}
+		}
+	}()
+	wg.Wait()
+
+	last, err := l.Append([]byte("torn"))
+	if err != nil {
+		t.Fatalf("error in appending record :: %v", err)
+	}
+	if err := l.Close(); err != nil {
+		t.Fatalf("error in closing log :: %v", err)
+	}
+
+	// Corrupt the last record to simulate a torn write
+	f, err := os.OpenFile(testPath, os.O_RDWR, 0)
+	if err != nil {
+		t.Fatalf("error in opening file :: %v", err)
+	}
+	if _, err := f.WriteAt([]byte("x"), last+recordHeaderSize); err != nil {
+		t.Fatalf("error in writing to file :: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatalf("error in closing file :: %v", err)
+	}
+
+	l, err = Open(testPath)
+	if err != nil {
+		t.Fatalf("error in opening log :: %v", err)
+	}
+	if l.Size() != last {
+		t.Fatalf("size of recovered log %d, expected %d", l.Size(), last)
+	}
+	if offset, err := l.Append([]byte("after")); err != nil || offset != last {
+		t.Fatalf("error in appending record after recovery :: %v", err)
+	}
+	if err := l.Close(); err != nil {
+		t.Fatalf("error in closing log :: %v", err)
+	}
+}
+
+func TestZeroedTail(t *testing.T) {
+	t.Parallel()
+
+	testPath := path.Join(t.TempDir(), "m.log")
+	l, err := Open(testPath)
+	if err != nil {
+		t.Fatalf("error in opening log :: %v", err)
+	}
+	for _, r := range records()[:3] {
+		if _, err := l.Append(r); err != nil {
+			t.Fatalf("error in appending record :: %v", err)
+		}
+	}
+	size := l.Size()
+	if err := l.Close(); err != nil {
+		t.Fatalf("error in closing log :: %v", err)
+	}
+
+	// Cursor written back to disk before the records it points past
+	f, err := os.OpenFile(testPath, os.O_RDWR, 0)
+	if err != nil {
+		t.Fatalf("error in opening file :: %v", err)
+	}
+	cursor := binary.LittleEndian.AppendUint64(nil, uint64(size+3*recordHeaderSize))
+	if _, err := f.WriteAt(cursor, cursorOffset); err != nil {
+		t.Fatalf("error in writing to file :: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatalf("error in closing file :: %v", err)
+	}
+
+	l, err = Open(testPath)
+	if err != nil {
+		t.Fatalf("error in opening log :: %v", err)
+	}
+	if l.Size() != size {
+		t.Fatalf("size of recovered log %d, expected %d", l.Size(), size)
+	}
+	count := 0
+	if err := l.Iterate(First, func(int64, []byte) error { count++; return nil }); err != nil || count != 3 {
+		t.Fatalf("unexpected result from Iterate, records: %d, err: %v", count, err)
+	}
+	if err := l.Close(); err != nil {
+		t.Fatalf("error in closing log :: %v", err)
+	}
+}
+
+func TestOpenCorrupted(t *testing.T) {
+	t.Parallel()
+
+	testPath := path.Join(t.TempDir(), "m.log")
+	if err := os.WriteFile(testPath, []byte("0123456789ABCDEFGHIJ"), 0644); err != nil {
+		t.Fatalf("error in writing file :: %v", err)
+	}
+
+	if _, err := Open(testPath); !errors.Is(err, ErrCorrupted) {
+		t.Fatalf("different error than expected in Open :: %v", err)
+	}
+}
@@ -3,6 +3,8 @@ package mmap
 import (
 	"errors"
 	"os"
+	"sync"
+	"sync/atomic"
 	"syscall"
 )
 
@@ -11,13 +13,50 @@ var (
 	ErrUnmappedMemory = errors.New("unmapped memory")
 	// ErrIndexOutOfBound is returned when given offset lies beyond the mapped region.
 	ErrIndexOutOfBound = errors.New("offset out of mapped region")
+	// ErrUnalignedOffset is returned when offset is not suitably aligned for an atomic operation.
+	ErrUnalignedOffset = errors.New("offset not aligned")
+	// ErrVarintOverflow is returned when a varint in mapped region overflows a 64-bit integer.
+	ErrVarintOverflow = errors.New("varint overflows a 64-bit integer")
+	// ErrUnsupportedType is returned when a type is not suitable to be stored in mapped memory.
+	ErrUnsupportedType = errors.New("type not supported in mapped memory")
+	// ErrReadOnly is returned when writing to the pages of mapped region without write permission.
+	ErrReadOnly = errors.New("mapped memory is read only")
+	// ErrNotResizable is returned when resizing a mapping that cannot be resized.
+	ErrNotResizable = errors.New("mapping cannot be resized")
+	// ErrMappingInUse is returned when unmapping or resizing a mapping with unreleased views.
+	ErrMappingInUse = errors.New("mapping in use by views")
+	// ErrOwnerDead is returned when a shared lock is acquired from a process that died holding it.
+	ErrOwnerDead = errors.New("owner of the lock died")
+	// ErrInvalidCapacity is returned when the capacity of a ring buffer is not a multiple
+	// of page size or does not match the capacity of the existing ring buffer.
+	ErrInvalidCapacity = errors.New("invalid ring buffer capacity")
 )
 
 // File provides abstraction around a memory mapped file.
 type File struct {
-	data   []byte
-	length int64
-	dirty  bool
+	mu            sync.RWMutex
+	concurrent    bool
+	recoverFaults bool
+	views         atomic.Int64
+
+	mapping []byte
+	data    []byte
+	// length is the accessible length of the mapped region, it is less than
+	// mappedLength if the backing file is shorter than the mapped region.
+	length       int64
+	mappedLength int64
+
+	offset   int64
+	prot     int
+	flags    int
+	dirty    pageSet
+	readOnly pageSet
+	growth   GrowthPolicy
+	file     *os.File
+	ownsFile bool
+
+	readOnlyPages int
+	mixedProt     bool
 }
 
 // NewSharedFileMmap maps a file into memory starting at a given offset, for given length.
@@ -28,22 +67,136 @@ type File struct {
 //	          then all the mapped memory is accessible
 //	case 2 => if   file size <= memory region (offset + length)
 //	          then from offset to file size memory region is accessible
-func NewSharedFileMmap(f *os.File, offset int64, length int, prot int) (*File, error) {
-	data, err := syscall.Mmap(int(f.Fd()), offset, length, prot, syscall.MAP_SHARED)
+//
+// Accessing the memory region beyond the file size raises a memory fault which
+// crashes the process, unless the mapping is created using WithFaultRecovery
+// in which case the fault is reported as ErrIndexOutOfBound by the accessors.
+// Call Refresh to bound the accessible region by the current file size.
+func NewSharedFileMmap(f *os.File, offset int64, length int, prot int, opts ...Option) (*File, error) {
+	return newMmap(f, offset, length, prot, syscall.MAP_SHARED, newOptions(opts))
+}
+
+// NewPrivateFileMmap maps a file into memory starting at a given offset, for given
+// length, as a private copy-on-write mapping. Writes to the mapped region are visible
+// only to this mapping and are never written back to the file. Accessible memory
+// region follows the same cases as documented for NewSharedFileMmap.
+func NewPrivateFileMmap(f *os.File, offset int64, length int, prot int, opts ...Option) (*File, error) {
+	return newMmap(f, offset, length, prot, syscall.MAP_PRIVATE, newOptions(opts))
+}
+
+// NewAnonymousMmap creates a private memory mapping of given length that is
+// not backed by any file. The mapped memory is initialized to zero.
+func NewAnonymousMmap(length int, prot int, opts ...Option) (*File, error) {
+	return newMmap(nil, 0, length, prot, syscall.MAP_ANON|syscall.MAP_PRIVATE, newOptions(opts))
+}
+
+// NewSharedAnonymousMmap creates a shared memory mapping of given length that is
+// not backed by any file. The mapped memory is initialized to zero and is shared
+// with the child processes created after the mapping.
+func NewSharedAnonymousMmap(length int, prot int, opts ...Option) (*File, error) {
+	return newMmap(nil, 0, length, prot, syscall.MAP_ANON|syscall.MAP_SHARED, newOptions(opts))
+}
+
+func newMmap(f *os.File, offset int64, length int, prot int, flags int, o *options) (*File, error) {
+	if o.err != nil {
+		return nil, o.err
+	}
+
+	fd := -1
+	if f != nil {
+		fd = int(f.Fd())
+	}
+
+	flags |= o.mapFlags
+	data, err := mmap(o.addr, length, prot, flags, fd, offset)
 	if err != nil {
 		return nil, err
 	}
 
-	return &File{
-		data:   data,
-		length: int64(length),
-	}, nil
+	for _, advice := range o.advice {
+		if _, _, errno := syscall.Syscall(syscall.SYS_MADVISE, addrOf(data),
+			uintptr(len(data)), uintptr(advice)); errno != 0 {
+			_ = munmap(data)
+			return nil, errno
+		}
+	}
+
+	m := &File{
+		dirty:   newPageSet(numPages(length)),
+		mapping: data,
+		data:    data,
+		length:  int64(length),
+		offset:  offset,
+		prot:    prot,
+		flags:   flags,
+		growth:  o.growth,
+		file:    f,
+
+		concurrent:    o.concurrent,
+		recoverFaults: o.recoverFaults,
+		readOnly:      newPageSet(numPages(length)),
+		mappedLength:  int64(length),
+	}
+	if prot&syscall.PROT_WRITE == 0 {
+		m.readOnly.add(0, numPages(length)-1)
+		m.readOnlyPages = numPages(length)
+	}
+
+	return m, nil
+}
+
+// fileBacked returns true if modifications to the mapped region are
+// carried through to a file on disk.
+func (m *File) fileBacked() bool {
+	return m.flags&syscall.MAP_SHARED != 0 && m.flags&syscall.MAP_ANON == 0
 }
 
 // Unmap unmaps the memory mapped file. An error will be returned
 // if any of the functions are called on Mmap after calling Unmap.
+// Unmap neither flushes the mapped region nor closes the file
+// opened using Open or Create, use Close instead. Unmap fails with
+// ErrMappingInUse if any View of the mapping is not yet released.
 func (m *File) Unmap() error {
-	err := syscall.Munmap(m.data)
+	m.lock()
+	defer m.unlock()
+
+	return m.unmap()
+}
+
+func (m *File) unmap() error {
+	if m.views.Load() > 0 {
+		return ErrMappingInUse
+	}
+
+	err := munmap(m.mapping)
+	m.mapping = nil
 	m.data = nil
+	m.dirty = nil
+	m.readOnly = nil
+	m.readOnlyPages = 0
 	return err
 }
+
+// Close flushes the modifications in the mapped region to disk, unmaps the
+// memory and closes the file if it was opened using Open or Create. If any
+// View of the mapping is not yet released, Close returns ErrMappingInUse
+// and can be called again after releasing the views.
+func (m *File) Close() error {
+	errFlush := m.Flush(syscall.MS_SYNC)
+
+	m.lock()
+	defer m.unlock()
+
+	var errUnmap, errClose error
+	if m.data != nil {
+		if errUnmap = m.unmap(); errors.Is(errUnmap, ErrMappingInUse) {
+			return errors.Join(errFlush, errUnmap)
+		}
+	}
+	if m.ownsFile {
+		errClose = m.file.Close()
+		m.ownsFile = false
+	}
+
+	return errors.Join(errFlush, errUnmap, errClose)
+}
@@ -0,0 +1,151 @@
+package mmap
+
+import (
+	"sync/atomic"
+	"unsafe"
+)
+
+// AtomicLoadUint32At atomically loads uint32 from offset.
+// Offset must be aligned to 4 bytes in memory.
+func (m *File) AtomicLoadUint32At(offset int64) uint32 {
+	m.rlock()
+	defer m.runlock()
+	if m.recoverFaults {
+		defer faultChecks(panicOnFault())
+	}
+
+	return atomic.LoadUint32(m.pointer32(offset))
+}
+
+// AtomicStoreUint32At atomically stores num at offset.
+// Offset must be aligned to 4 bytes in memory.
+func (m *File) AtomicStoreUint32At(num uint32, offset int64) {
+	m.rlock()
+	defer m.runlock()
+	if m.recoverFaults {
+		defer faultChecks(panicOnFault())
+	}
+
+	p := m.pointer32(offset)
+	m.readOnlyChecks(offset, 4)
+	atomic.StoreUint32(p, num)
+	m.markDirty(offset, 4)
+}
+
+// AtomicAddUint32At atomically adds delta to uint32 at offset and
+// returns the new value. Offset must be aligned to 4 bytes in memory.
+func (m *File) AtomicAddUint32At(delta uint32, offset int64) uint32 {
+	m.rlock()
+	defer m.runlock()
+	if m.recoverFaults {
+		defer faultChecks(panicOnFault())
+	}
+
+	p := m.pointer32(offset)
+	m.readOnlyChecks(offset, 4)
+	n := atomic.AddUint32(p, delta)
+	m.markDirty(offset, 4)
+	return n
+}
+
+// CompareAndSwapUint32At executes the compare-and-swap operation for uint32
+// at offset. Offset must be aligned to 4 bytes in memory.
+func (m *File) CompareAndSwapUint32At(old, replacement uint32, offset int64) bool {
+	m.rlock()
+	defer m.runlock()
+	if m.recoverFaults {
+		defer faultChecks(panicOnFault())
+	}
+
+	p := m.pointer32(offset)
+	m.readOnlyChecks(offset, 4)
+	swapped := atomic.CompareAndSwapUint32(p, old, replacement)
+	if swapped {
+		m.markDirty(offset, 4)
+	}
+	return swapped
+}
+
+// AtomicLoadUint64At atomically loads uint64 from offset.
+// Offset must be aligned to 8 bytes in memory.
+func (m *File) AtomicLoadUint64At(offset int64) uint64 {
+	m.rlock()
+	defer m.runlock()
+	if m.recoverFaults {
+		defer faultChecks(panicOnFault())
+	}
+
+	return atomic.LoadUint64(m.pointer64(offset))
+}
+
+// AtomicStoreUint64At atomically stores num at offset.
+// Offset must be aligned to 8 bytes in memory.
+func (m *File) AtomicStoreUint64At(num uint64, offset int64) {
+	m.rlock()
+	defer m.runlock()
+	if m.recoverFaults {
+		defer faultChecks(panicOnFault())
+	}
+
+	p := m.pointer64(offset)
+	m.readOnlyChecks(offset, 8)
+	atomic.StoreUint64(p, num)
+	m.markDirty(offset, 8)
+}
+
+// AtomicAddUint64At atomically adds delta to uint64 at offset and
+// returns the new value. Offset must be aligned to 8 bytes in memory.
+func (m *File) AtomicAddUint64At(delta uint64, offset int64) uint64 {
+	m.rlock()
+	defer m.runlock()
+	if m.recoverFaults {
+		defer faultChecks(panicOnFault())
+	}
+
+	p := m.pointer64(offset)
+	m.readOnlyChecks(offset, 8)
+	n := atomic.AddUint64(p, delta)
+	m.markDirty(offset, 8)
+	return n
+}
+
+// CompareAndSwapUint64At executes the compare-and-swap operation for uint64
+// at offset. Offset must be aligned to 8 bytes in memory.
+func (m *File) CompareAndSwapUint64At(old, replacement uint64, offset int64) bool {
+	m.rlock()
+	defer m.runlock()
+	if m.recoverFaults {
+		defer faultChecks(panicOnFault())
+	}
+
+	p := m.pointer64(offset)
+	m.readOnlyChecks(offset, 8)
+	swapped := atomic.CompareAndSwapUint64(p, old, replacement)
+	if swapped {
+		m.markDirty(offset, 8)
+	}
+	return swapped
+}
+
+// pointer32 returns pointer to uint32 at offset after boundary and alignment checks.
+func (m *File) pointer32(offset int64) *uint32 {
+	return (*uint32)(m.alignedPointer(offset, 4))
+}
+
+// pointer64 returns pointer to uint64 at offset after boundary and alignment checks.
+func (m *File) pointer64(offset int64) *uint64 {
+	return (*uint64)(m.alignedPointer(offset, 8))
+}
+
+// alignedPointer panics if size bytes at offset are out of the mapped region or
+// not aligned to size bytes in memory. Caller must hold the read lock while
+// accessing the returned pointer.
+func (m *File) alignedPointer(offset, size int64) unsafe.Pointer {
+	m.boundaryChecks(offset, size)
+	p := unsafe.Pointer(&m.data[offset])
+	if uintptr(p)%uintptr(size) != 0 {
+		panic(ErrUnalignedOffset)
+	}
+
+	return p
+}
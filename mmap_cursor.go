@@ -0,0 +1,171 @@
+package mmap
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+var errInvalidUnreadByte = errors.New("invalid use of UnreadByte")
+
+// Cursor provides sequential access to a section of the mapped region.
+// Cursor implements io.Reader, io.Writer, io.Seeker, io.ByteReader,
+// io.ByteScanner and io.WriterTo interfaces. A Cursor must not be
+// used concurrently by multiple goroutines.
+type Cursor struct {
+	m        *File
+	base     int64
+	limit    int64
+	pos      int64
+	readByte bool
+}
+
+// NewCursor returns a Cursor to access length bytes of the mapped region
+// starting at given offset. If the mapped region is shrunk afterwards, the
+// section ends at the end of the mapped region.
+func (m *File) NewCursor(offset, length int64) (*Cursor, error) {
+	m.rlock()
+	defer m.runlock()
+
+	if err := m.checkBounds(offset, length); err != nil {
+		return nil, err
+	}
+
+	return &Cursor{m: m, base: offset, limit: length}, nil
+}
+
+// Size returns the size of the section accessed by the cursor.
+func (c *Cursor) Size() int64 {
+	return c.limit
+}
+
+// Read reads up to len(p) bytes from the section into p.
+func (c *Cursor) Read(p []byte) (int, error) {
+	c.readByte = false
+	if c.pos >= c.limit {
+		return 0, io.EOF
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	c.m.rlock()
+	defer c.m.runlock()
+
+	section, err := c.remaining()
+	if err != nil {
+		return 0, err
+	}
+
+	var n int
+	err = c.m.checkFault(func() {
+		n = copy(p, section)
+	})
+	c.pos += int64(n)
+	return n, err
+}
+
+// Write writes len(p) bytes from p to the section. Write returns
+// io.ErrShortWrite if p does not fit in the remaining section.
+func (c *Cursor) Write(p []byte) (int, error) {
+	c.readByte = false
+	if len(p) == 0 {
+		return 0, nil
+	}
+	if c.pos >= c.limit {
+		return 0, io.ErrShortWrite
+	}
+
+	n, err := c.m.TryWriteAt(p[:min(int64(len(p)), c.limit-c.pos)], c.base+c.pos)
+	c.pos += int64(n)
+	if err == nil && n < len(p) {
+		err = io.ErrShortWrite
+	}
+	return n, err
+}
+
+// Seek sets the position for the next Read or Write relative to the start of the section.
+func (c *Cursor) Seek(offset int64, whence int) (int64, error) {
+	c.readByte = false
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += c.pos
+	case io.SeekEnd:
+		offset += c.limit
+	default:
+		return 0, fmt.Errorf("invalid whence %d", whence)
+	}
+	if offset < 0 {
+		return 0, fmt.Errorf("negative position %d", offset)
+	}
+
+	c.pos = offset
+	return offset, nil
+}
+
+// ReadByte reads and returns the next byte from the section.
+func (c *Cursor) ReadByte() (byte, error) {
+	var b [1]byte
+	if _, err := c.Read(b[:]); err != nil {
+		return 0, err
+	}
+
+	c.readByte = true
+	return b[0], nil
+}
+
+// UnreadByte unreads the last byte read by ReadByte.
+func (c *Cursor) UnreadByte() error {
+	if !c.readByte {
+		return errInvalidUnreadByte
+	}
+
+	c.readByte = false
+	c.pos--
+	return nil
+}
+
+// WriteTo writes the remaining section to w directly from the mapped
+// region without an intermediate copy. The mapped region is read locked
+// while w.Write is being called, hence, w must not call Unmap, Close,
+// Resize or Grow on the File and must not retain the written slice.
+// Memory faults raised while w.Write accesses the mapped region are
+// not recovered, as documented for View.Bytes.
+func (c *Cursor) WriteTo(w io.Writer) (int64, error) {
+	c.readByte = false
+	if c.pos >= c.limit {
+		return 0, nil
+	}
+
+	c.m.rlock()
+	defer c.m.runlock()
+
+	section, err := c.remaining()
+	if err == io.EOF {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+
+	n, err := w.Write(section)
+	c.pos += int64(n)
+	if err == nil && n < len(section) {
+		err = io.ErrShortWrite
+	}
+	return int64(n), err
+}
+
+// remaining returns the mapped memory of the remaining section, or io.EOF
+// if the section ends at the current position. Caller must hold the read lock.
+func (c *Cursor) remaining() ([]byte, error) {
+	if c.m.data == nil {
+		return nil, ErrUnmappedMemory
+	}
+
+	start, end := c.base+c.pos, min(c.base+c.limit, c.m.length)
+	if start >= end {
+		return nil, io.EOF
+	}
+	return c.m.data[start:end], nil
+}
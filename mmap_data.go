@@ -4,19 +4,27 @@ import (
 	"encoding/binary"
 	"strings"
 	"syscall"
-	"unsafe"
 )
 
 // boundaryChecks panics if m.data is nil or numBytes cannot be
 // read or written in the mapped file starting at given offset.
+// Functions prefixed with Try return an error from checkBounds instead.
 func (m *File) boundaryChecks(offset, numBytes int64) {
 	if m.data == nil {
 		panic(ErrUnmappedMemory)
-	} else if offset+numBytes > m.length || offset < 0 {
+	} else if offset < 0 || numBytes < 0 || offset > m.length-numBytes {
 		panic(ErrIndexOutOfBound)
 	}
 }
 
+// mustGrow extends the mapping as per the growth policy if required
+// to write numBytes at offset and panics if the mapping cannot be grown.
+func (m *File) mustGrow(offset, numBytes int64) {
+	if err := m.autoGrow(offset, numBytes); err != nil {
+		panic(err)
+	}
+}
+
 // ReadAt copies data to dest slice from mapped region starting at
 // given offset and returns number of bytes copied to the dest slice.
 // There are two possibilities -
@@ -28,8 +36,14 @@ func (m *File) boundaryChecks(offset, numBytes int64) {
 //
 // err is always nil, hence, can be ignored.
 func (m *File) ReadAt(dest []byte, offset int64) (int, error) {
+	m.rlock()
+	defer m.runlock()
+	if m.recoverFaults {
+		defer faultChecks(panicOnFault())
+	}
+
 	m.boundaryChecks(offset, 1)
-	return copy(dest, m.data[offset:]), nil
+	return copy(dest, m.data[offset:m.length]), nil
 }
 
 // WriteAt copies data to mapped region from the src slice starting at
@@ -41,17 +55,37 @@ func (m *File) ReadAt(dest []byte, offset int64) (int, error) {
 //	Case 2: len(src) < (m.length - offset)
 //	    => copies len(src) bytes to the mapped region from src
 //
-// err is always nil, hence, can be ignored.
+// If a growth policy is set, the mapping is extended to fit all of src instead.
+// err is nil unless the mapping could not be grown, hence, can be ignored
+// for mappings without a growth policy.
 func (m *File) WriteAt(src []byte, offset int64) (int, error) {
+	if err := m.autoGrow(offset, int64(len(src))); err != nil {
+		return 0, err
+	}
+
+	m.rlock()
+	defer m.runlock()
+	if m.recoverFaults {
+		defer faultChecks(panicOnFault())
+	}
+
 	m.boundaryChecks(offset, 1)
-	m.dirty = true
-	return copy(m.data[offset:], src), nil
+	m.readOnlyChecks(offset, min(int64(len(src)), m.length-offset))
+	n := copy(m.data[offset:m.length], src)
+	m.markDirty(offset, int64(n))
+	return n, nil
 }
 
 // ReadStringAt copies data to dest string builder from mapped region starting at
 // given offset until the min value of (length - offset) or (dest.Cap() - dest.Len())
 // or maxLength and returns number of bytes copied to the dest slice.
 func (m *File) ReadStringAt(dest *strings.Builder, offset, maxLength int64) int {
+	m.rlock()
+	defer m.runlock()
+	if m.recoverFaults {
+		defer faultChecks(panicOnFault())
+	}
+
 	m.boundaryChecks(offset, 1)
 
 	dataLength := min(m.length-offset, int64(dest.Cap()-dest.Len()), maxLength)
@@ -62,38 +96,84 @@ func (m *File) ReadStringAt(dest *strings.Builder, offset, maxLength int64) int
 
 // WriteStringAt copies data to mapped region from the src string starting at
 // given offset and returns number of bytes copied to the mapped region.
+// If a growth policy is set, the mapping is extended to fit all of src.
 func (m *File) WriteStringAt(src string, offset int64) int {
+	m.mustGrow(offset, int64(len(src)))
+
+	m.rlock()
+	defer m.runlock()
+	if m.recoverFaults {
+		defer faultChecks(panicOnFault())
+	}
+
 	m.boundaryChecks(offset, 1)
-	m.dirty = true
-	return copy(m.data[offset:], src)
+	m.readOnlyChecks(offset, min(int64(len(src)), m.length-offset))
+	n := copy(m.data[offset:m.length], src)
+	m.markDirty(offset, int64(n))
+	return n
 }
 
 // ReadUint64At reads uint64 from offset.
 func (m *File) ReadUint64At(offset int64) uint64 {
-	m.boundaryChecks(offset, 8)
-	return binary.LittleEndian.Uint64(m.data[offset : offset+8])
+	return m.readUint64(binary.LittleEndian, offset)
 }
 
 // WriteUint64At writes num at offset.
 func (m *File) WriteUint64At(num uint64, offset int64) {
-	m.boundaryChecks(offset, 8)
-	m.dirty = true
-	binary.LittleEndian.PutUint64(m.data[offset:offset+8], num)
+	m.writeUint64(binary.LittleEndian, num, offset)
 }
 
-// Flush flushes the memory mapped region to disk. Flush makes a
-// syscall only if the memory region is modified since the last flush.
+// Flush flushes the modified pages of memory mapped region to disk. Flush
+// makes a syscall for each contiguous range of pages modified since the last
+// flush. Flush is a no-op for anonymous and private mappings because
+// modifications to such mappings are never written to a file.
 func (m *File) Flush(flags int) error {
-	if !m.dirty {
+	m.rlock()
+	defer m.runlock()
+
+	dirty := m.dirty.take()
+	if !m.fileBacked() {
+		return nil
+	}
+
+	for _, r := range m.dirtyRanges(dirty) {
+		if err := m.pageSyscall(syscall.SYS_MSYNC, r.Offset, r.Length, flags); err != nil {
+			m.dirty.merge(dirty)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// FlushRange flushes the pages of memory mapped region overlapping length
+// bytes starting at given offset to disk and marks these pages as not modified.
+// Unlike Flush, FlushRange always makes a syscall irrespective of whether the
+// pages are modified. FlushRange is a no-op for anonymous and private mappings.
+func (m *File) FlushRange(offset, length int64, flags int) error {
+	m.rlock()
+	defer m.runlock()
+
+	if err := m.checkBounds(offset, length); err != nil || !m.fileBacked() {
+		return err
+	}
+
+	return m.flushRange(offset, length, flags)
+}
+
+// flushRange flushes the pages overlapping the given valid range and marks
+// them as not modified. Caller must hold the read lock.
+func (m *File) flushRange(offset, length int64, flags int) error {
+	if length <= 0 {
 		return nil
 	}
 
-	_, _, err := syscall.Syscall(syscall.SYS_MSYNC,
-		uintptr(unsafe.Pointer(&m.data[0])), uintptr(m.length), uintptr(flags))
-	if err != 0 {
+	first, last := m.pagesOf(offset, length)
+	m.dirty.remove(first, last)
+	if err := m.pageSyscall(syscall.SYS_MSYNC, offset, length, flags); err != nil {
+		m.dirty.add(first, last)
 		return err
 	}
 
-	m.dirty = false
 	return nil
 }
@@ -0,0 +1,170 @@
+package mmap
+
+import "sync/atomic"
+
+// Range represents length bytes of the mapped region starting at Offset.
+type Range struct {
+	Offset int64
+	Length int64
+}
+
+// DirtyRanges returns the ranges of the mapped region modified since the last
+// flush. Modifications are tracked at the granularity of pages, hence, ranges
+// cover whole pages except at the boundaries of the mapped region. Adjacent
+// modified pages are coalesced into a single range.
+func (m *File) DirtyRanges() []Range {
+	m.rlock()
+	defer m.runlock()
+
+	return m.dirtyRanges(m.dirty)
+}
+
+// dirtyRanges converts the pages in the set to ranges of the mapped region.
+func (m *File) dirtyRanges(s pageSet) []Range {
+	pageOffset := m.pageOffset()
+
+	var ranges []Range
+	for _, run := range s.runs() {
+		start := max(int64(run[0]*pageSize)-pageOffset, 0)
+		end := min(int64(run[1]*pageSize)-pageOffset, m.length)
+		if start < end {
+			ranges = append(ranges, Range{Offset: start, Length: end - start})
+		}
+	}
+
+	return ranges
+}
+
+// markDirty marks the pages overlapping numBytes starting at offset as modified.
+// Pages must be marked after modifying the data so that a concurrent Flush
+// never misses the modification.
+func (m *File) markDirty(offset, numBytes int64) {
+	if numBytes <= 0 {
+		return
+	}
+
+	first, last := m.pagesOf(offset, numBytes)
+	m.dirty.add(first, last)
+}
+
+// pagesOf returns index of the first and last page of the
+// mapping overlapping numBytes starting at given offset.
+func (m *File) pagesOf(offset, numBytes int64) (int, int) {
+	start := m.pageOffset() + offset
+	return int(start / int64(pageSize)), int((start + numBytes - 1) / int64(pageSize))
+}
+
+// pageOffset returns the offset of the mapped region from the start of the
+// mapping, it is non-zero when the offset in file is not page aligned.
+func (m *File) pageOffset() int64 {
+	return int64(len(m.mapping) - len(m.data))
+}
+
+// numPages returns the number of pages needed to map length bytes.
+func numPages(length int) int {
+	return (length + pageSize - 1) / pageSize
+}
+
+// pageSet is a bitmap with a bit for each page of the mapping. Pages can be
+// added and removed concurrently, all the words are accessed atomically.
+type pageSet []uint64
+
+func newPageSet(numPages int) pageSet {
+	return make(pageSet, (numPages+63)/64)
+}
+
+// add adds all the pages from first to last (both inclusive) to the set.
+func (s pageSet) add(first, last int) {
+	for i := first / 64; i <= last/64; i++ {
+		atomic.OrUint64(&s[i], wordMask(i, first, last))
+	}
+}
+
+// remove removes all the pages from first to last (both inclusive) from the set.
+func (s pageSet) remove(first, last int) {
+	for i := first / 64; i <= last/64; i++ {
+		atomic.AndUint64(&s[i], ^wordMask(i, first, last))
+	}
+}
+
+// wordMask returns the bits of i-th word for pages from first to last.
+func wordMask(i, first, last int) uint64 {
+	lo := max(first-i*64, 0)
+	hi := min(last-i*64, 63)
+	return (^uint64(0) >> (63 - hi)) & (^uint64(0) << lo)
+}
+
+// load atomically loads the i-th word of the set.
+func (s pageSet) load(i int) uint64 {
+	return atomic.LoadUint64(&s[i])
+}
+
+// take removes all the pages from the set and returns them as a new set.
+func (s pageSet) take() pageSet {
+	taken := make(pageSet, len(s))
+	for i := range s {
+		taken[i] = atomic.SwapUint64(&s[i], 0)
+	}
+
+	return taken
+}
+
+// merge adds all the pages in other set to the set.
+func (s pageSet) merge(other pageSet) {
+	for i, word := range other {
+		atomic.OrUint64(&s[i], word)
+	}
+}
+
+// empty returns true if there are no pages in the set.
+func (s pageSet) empty() bool {
+	for i := range s {
+		if s.load(i) != 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// runs returns contiguous runs of pages in the set as [start, end) pairs.
+func (s pageSet) runs() [][2]int {
+	var runs [][2]int
+	start := -1
+	for i := range s {
+		word := s.load(i)
+		if (start == -1 && word == 0) || (start != -1 && word == ^uint64(0)) {
+			continue
+		}
+
+		for b := range 64 {
+			p := i*64 + b
+			if word&(1<<b) != 0 {
+				if start == -1 {
+					start = p
+				}
+			} else if start != -1 {
+				runs = append(runs, [2]int{start, p})
+				start = -1
+			}
+		}
+	}
+	if start != -1 {
+		runs = append(runs, [2]int{start, len(s) * 64})
+	}
+
+	return runs
+}
+
+// resized returns a copy of the set that can hold numPages pages, pages
+// beyond numPages are removed from the returned set. resized must not
+// be called concurrently with other functions on the set.
+func (s pageSet) resized(numPages int) pageSet {
+	r := newPageSet(numPages)
+	copy(r, s)
+	if rem := numPages % 64; rem != 0 {
+		r[len(r)-1] &= 1<<rem - 1
+	}
+
+	return r
+}
@@ -0,0 +1,126 @@
+package mmap
+
+import (
+	"fmt"
+	"runtime/debug"
+)
+
+// WithFaultRecovery makes the functions accessing the mapped region report
+// memory faults, such as the SIGBUS raised when accessing pages beyond the
+// end of a truncated file, as ErrIndexOutOfBound instead of crashing the
+// process. Recovering from faults adds overhead to every access, hence, it
+// is only worth enabling if the backing file may be truncated while mapped.
+func WithFaultRecovery() Option {
+	return func(o *options) {
+		o.recoverFaults = true
+	}
+}
+
+// panicOnFault makes a memory fault in the calling goroutine panic instead of
+// crashing the process and returns the previous setting, which is restored by
+// faultChecks. Functions accessing the mapped region recover from faults only
+// if the mapping is created using WithFaultRecovery as
+//
+//	if m.recoverFaults {
+//		defer faultChecks(panicOnFault())
+//	}
+//
+// so that the other mappings do not pay for it. Functions
+// prefixed with Try use checkFault instead of faultChecks.
+func panicOnFault() bool {
+	return debug.SetPanicOnFault(true)
+}
+
+// faultChecks restores the old setting of panicOnFault and converts
+// a panic caused by a memory fault into a panic with ErrIndexOutOfBound.
+func faultChecks(old bool) {
+	debug.SetPanicOnFault(old)
+	if r := recover(); r != nil {
+		if faultError(r) != nil {
+			panic(ErrIndexOutOfBound)
+		}
+		panic(r)
+	}
+}
+
+// checkFault calls access, which accesses the mapped region, and returns an
+// error wrapping ErrIndexOutOfBound if access raises a memory fault and the
+// mapping is created using WithFaultRecovery. It returns nil otherwise.
+func (m *File) checkFault(access func()) error {
+	if !m.recoverFaults {
+		access()
+		return nil
+	}
+
+	return recoverFault(access)
+}
+
+// recoverFault calls access and returns the memory fault raised by it as an
+// error. It is not inlined to keep checkFault cheap enough to be inlined.
+//
+//go:noinline
+func recoverFault(access func()) error {
+	var err error
+	func() {
+		old := panicOnFault()
+		defer func() {
+			debug.SetPanicOnFault(old)
+			if r := recover(); r != nil {
+				if err = faultError(r); err == nil {
+					panic(r)
+				}
+			}
+		}()
+
+		access()
+	}()
+
+	return err
+}
+
+// faultError returns an error wrapping ErrIndexOutOfBound if r is a
+// panic caused by a memory fault, and nil otherwise.
+func faultError(r any) error {
+	fault, ok := r.(interface{ Addr() uintptr })
+	if !ok {
+		return nil
+	}
+
+	return fmt.Errorf("%w: memory fault at address %#x", ErrIndexOutOfBound, fault.Addr())
+}
+
+// Refresh re-stats the backing file and bounds the accessible region of the
+// mapping by the current file size. Accessing mapped pages that lie beyond
+// the end of the file faults, hence, Refresh should be called whenever the
+// backing file may have been truncated or extended by another process. The
+// accessible region never exceeds the mapped region, use Resize for that.
+// Refresh does nothing for anonymous mappings.
+func (m *File) Refresh() error {
+	m.lock()
+	defer m.unlock()
+
+	if m.data == nil {
+		return ErrUnmappedMemory
+	}
+	if m.file == nil {
+		return nil
+	}
+
+	info, err := m.file.Stat()
+	if err != nil {
+		return err
+	}
+
+	size := info.Size() - m.offset - m.pageOffset()
+	m.length = max(min(size, m.mappedLength), 0)
+	return nil
+}
+
+// ValidLength returns the length of the accessible region of the mapping,
+// as of the last call to Refresh or Resize.
+func (m *File) ValidLength() int64 {
+	m.rlock()
+	defer m.runlock()
+
+	return m.length
+}
@@ -0,0 +1,151 @@
+package mmap
+
+import (
+	"encoding/binary"
+	"math"
+	"unsafe"
+)
+
+// ReadFloat32At reads float32 from offset.
+func (m *File) ReadFloat32At(offset int64) float32 {
+	return math.Float32frombits(m.readUint32(binary.LittleEndian, offset))
+}
+
+// WriteFloat32At writes num at offset.
+func (m *File) WriteFloat32At(num float32, offset int64) {
+	m.writeUint32(binary.LittleEndian, math.Float32bits(num), offset)
+}
+
+// ReadFloat64At reads float64 from offset.
+func (m *File) ReadFloat64At(offset int64) float64 {
+	return math.Float64frombits(m.readUint64(binary.LittleEndian, offset))
+}
+
+// WriteFloat64At writes num at offset.
+func (m *File) WriteFloat64At(num float64, offset int64) {
+	m.writeUint64(binary.LittleEndian, math.Float64bits(num), offset)
+}
+
+// ReadComplex64At reads complex64 from offset, real part followed by imaginary part.
+func (m *File) ReadComplex64At(offset int64) complex64 {
+	m.rlock()
+	defer m.runlock()
+	if m.recoverFaults {
+		defer faultChecks(panicOnFault())
+	}
+
+	m.boundaryChecks(offset, 8)
+	return complex(
+		math.Float32frombits(binary.LittleEndian.Uint32(m.data[offset:offset+4])),
+		math.Float32frombits(binary.LittleEndian.Uint32(m.data[offset+4:offset+8])))
+}
+
+// WriteComplex64At writes num at offset, real part followed by imaginary part.
+func (m *File) WriteComplex64At(num complex64, offset int64) {
+	m.mustGrow(offset, 8)
+
+	m.rlock()
+	defer m.runlock()
+	if m.recoverFaults {
+		defer faultChecks(panicOnFault())
+	}
+
+	m.writeChecks(offset, 8)
+	binary.LittleEndian.PutUint32(m.data[offset:offset+4], math.Float32bits(real(num)))
+	binary.LittleEndian.PutUint32(m.data[offset+4:offset+8], math.Float32bits(imag(num)))
+	m.markDirty(offset, 8)
+}
+
+// ReadComplex128At reads complex128 from offset, real part followed by imaginary part.
+func (m *File) ReadComplex128At(offset int64) complex128 {
+	m.rlock()
+	defer m.runlock()
+	if m.recoverFaults {
+		defer faultChecks(panicOnFault())
+	}
+
+	m.boundaryChecks(offset, 16)
+	return complex(
+		math.Float64frombits(binary.LittleEndian.Uint64(m.data[offset:offset+8])),
+		math.Float64frombits(binary.LittleEndian.Uint64(m.data[offset+8:offset+16])))
+}
+
+// WriteComplex128At writes num at offset, real part followed by imaginary part.
+func (m *File) WriteComplex128At(num complex128, offset int64) {
+	m.mustGrow(offset, 16)
+
+	m.rlock()
+	defer m.runlock()
+	if m.recoverFaults {
+		defer faultChecks(panicOnFault())
+	}
+
+	m.writeChecks(offset, 16)
+	binary.LittleEndian.PutUint64(m.data[offset:offset+8], math.Float64bits(real(num)))
+	binary.LittleEndian.PutUint64(m.data[offset+8:offset+16], math.Float64bits(imag(num)))
+	m.markDirty(offset, 16)
+}
+
+// ReadFloat32sAt fills dest with len(dest) float32 values stored
+// contiguously in the mapped region starting at offset.
+func (m *File) ReadFloat32sAt(dest []float32, offset int64) {
+	b := unsafe.Slice((*byte)(unsafe.Pointer(unsafe.SliceData(dest))), 4*len(dest))
+
+	m.rlock()
+	defer m.runlock()
+	if m.recoverFaults {
+		defer faultChecks(panicOnFault())
+	}
+
+	m.boundaryChecks(offset, int64(len(b)))
+	copy(b, m.data[offset:m.length])
+}
+
+// WriteFloat32sAt writes all float32 values in src contiguously
+// in the mapped region starting at offset.
+func (m *File) WriteFloat32sAt(src []float32, offset int64) {
+	b := unsafe.Slice((*byte)(unsafe.Pointer(unsafe.SliceData(src))), 4*len(src))
+	m.mustGrow(offset, int64(len(b)))
+
+	m.rlock()
+	defer m.runlock()
+	if m.recoverFaults {
+		defer faultChecks(panicOnFault())
+	}
+
+	m.writeChecks(offset, int64(len(b)))
+	copy(m.data[offset:m.length], b)
+	m.markDirty(offset, int64(len(b)))
+}
+
+// ReadFloat64sAt fills dest with len(dest) float64 values stored
+// contiguously in the mapped region starting at offset.
+func (m *File) ReadFloat64sAt(dest []float64, offset int64) {
+	b := unsafe.Slice((*byte)(unsafe.Pointer(unsafe.SliceData(dest))), 8*len(dest))
+
+	m.rlock()
+	defer m.runlock()
+	if m.recoverFaults {
+		defer faultChecks(panicOnFault())
+	}
+
+	m.boundaryChecks(offset, int64(len(b)))
+	copy(b, m.data[offset:m.length])
+}
+
+// WriteFloat64sAt writes all float64 values in src contiguously
+// in the mapped region starting at offset.
+func (m *File) WriteFloat64sAt(src []float64, offset int64) {
+	b := unsafe.Slice((*byte)(unsafe.Pointer(unsafe.SliceData(src))), 8*len(src))
+	m.mustGrow(offset, int64(len(b)))
+
+	m.rlock()
+	defer m.runlock()
+	if m.recoverFaults {
+		defer faultChecks(panicOnFault())
+	}
+
+	m.writeChecks(offset, int64(len(b)))
+	copy(m.data[offset:m.length], b)
+	m.markDirty(offset, int64(len(b)))
+}
@@ -0,0 +1,32 @@
+package mmap
+
+import "time"
+
+// WaitUint32At blocks while uint32 at offset is equal to val until woken up by
+// WakeUint32At, possibly from another process sharing the mapping, or until
+// timeout elapses, in which case os.ErrDeadlineExceeded is returned. Negative
+// timeout waits indefinitely. WaitUint32At may return spuriously, hence, the
+// caller must check the value again. Offset must be aligned to 4 bytes in
+// memory. On linux, WaitUint32At uses futex whereas it polls the value on
+// other platforms.
+func (m *File) WaitUint32At(val uint32, offset int64, timeout time.Duration) error {
+	return m.wait(val, offset, timeout)
+}
+
+// WakeUint32At wakes up at most n goroutines or processes blocked in
+// WaitUint32At on the uint32 at offset and returns the number of woken
+// up waiters. Offset must be aligned to 4 bytes in memory. WakeUint32At
+// does nothing on platforms other than linux.
+func (m *File) WakeUint32At(offset int64, n int) (int, error) {
+	return m.wake(offset, n)
+}
+
+// futexAddr returns the address of uint32 at offset after boundary and
+// alignment checks. The mapping may be unmapped while a syscall is waiting
+// on the returned address, in which case, the syscall fails with EFAULT.
+func (m *File) futexAddr(offset int64) uintptr {
+	m.rlock()
+	defer m.runlock()
+
+	return uintptr(m.alignedPointer(offset, 4))
+}
@@ -0,0 +1,155 @@
+package mmap
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"unsafe"
+)
+
+// supportedTypes caches the result of checkType for each type.
+var supportedTypes sync.Map
+
+// Slice provides zero-copy access to consecutive values of type T stored in
+// the mapped region. Slice holds a View of the mapped region, hence, it must
+// be released by calling Release once the values are no longer accessed.
+type Slice[T any] struct {
+	view  *View
+	items []T
+}
+
+// SliceAt returns a Slice of count values of type T stored in the mapped region
+// starting at given offset. T must be a fixed-size type without any pointers,
+// i.e. booleans, numbers, and arrays and structs made of these, and must not
+// be zero-sized like struct{}. Offset must be aligned in memory as required
+// by T. Modifications made through the slice are not tracked by Flush, use
+// FlushRange to flush such modifications to disk.
+func SliceAt[T any](m *File, offset int64, count int) (*Slice[T], error) {
+	t, err := typeFor[T]()
+	if err != nil {
+		return nil, err
+	}
+	if count <= 0 {
+		return nil, fmt.Errorf("%w: invalid count %d", ErrIndexOutOfBound, count)
+	}
+
+	v, err := m.View(offset, int64(count)*int64(t.Size()))
+	if err != nil {
+		return nil, err
+	}
+
+	b := v.Bytes()
+	if addrOf(b)%uintptr(t.Align()) != 0 {
+		v.Release()
+		return nil, fmt.Errorf("%w: offset %d, alignment %d", ErrUnalignedOffset, offset, t.Align())
+	}
+
+	return &Slice[T]{view: v, items: unsafe.Slice((*T)(unsafe.Pointer(&b[0])), count)}, nil
+}
+
+// Items returns the values referring to the mapped region.
+// The returned slice must not be accessed after calling Release.
+func (s *Slice[T]) Items() []T {
+	if s.view.Bytes() == nil {
+		return nil
+	}
+
+	return s.items
+}
+
+// Release releases the underlying View of the mapped region.
+func (s *Slice[T]) Release() {
+	s.view.Release()
+}
+
+// ReadValueAt reads a value of type T stored in the mapped region at given
+// offset. T must satisfy the same constraints as documented for SliceAt,
+// except that offset need not be aligned.
+func ReadValueAt[T any](m *File, offset int64) (T, error) {
+	var value T
+	t, err := typeFor[T]()
+	if err != nil {
+		return value, err
+	}
+
+	m.rlock()
+	defer m.runlock()
+
+	if err := m.checkBounds(offset, int64(t.Size())); err != nil {
+		return value, err
+	}
+
+	err = m.checkFault(func() {
+		copy(unsafe.Slice((*byte)(unsafe.Pointer(&value)), t.Size()), m.data[offset:m.length])
+	})
+	return value, err
+}
+
+// WriteValueAt writes value of type T in the mapped region at given offset.
+// T must satisfy the same constraints as documented for SliceAt, except that
+// offset need not be aligned.
+func WriteValueAt[T any](m *File, value T, offset int64) error {
+	t, err := typeFor[T]()
+	if err != nil {
+		return err
+	}
+	if err := m.autoGrow(offset, int64(t.Size())); err != nil {
+		return err
+	}
+
+	m.rlock()
+	defer m.runlock()
+
+	if err := m.checkBounds(offset, int64(t.Size())); err != nil {
+		return err
+	}
+	if err := m.checkReadOnly(offset, int64(t.Size())); err != nil {
+		return err
+	}
+
+	err = m.checkFault(func() {
+		copy(m.data[offset:m.length], unsafe.Slice((*byte)(unsafe.Pointer(&value)), t.Size()))
+	})
+	m.markDirty(offset, int64(t.Size()))
+	return err
+}
+
+// typeFor returns reflect.Type of T if T can be stored in mapped memory.
+// Zero-sized types are rejected as they do not occupy any mapped memory.
+func typeFor[T any]() (reflect.Type, error) {
+	t := reflect.TypeFor[T]()
+	if supported, ok := supportedTypes.Load(t); ok {
+		if !supported.(bool) {
+			return nil, fmt.Errorf("%w: %v", ErrUnsupportedType, t)
+		}
+		return t, nil
+	}
+
+	supported := checkType(t) && t.Size() > 0
+	supportedTypes.Store(t, supported)
+	if !supported {
+		return nil, fmt.Errorf("%w: %v", ErrUnsupportedType, t)
+	}
+	return t, nil
+}
+
+// checkType returns true if t has a fixed size and does not contain any pointers.
+func checkType(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64, reflect.Complex64, reflect.Complex128:
+		return true
+	case reflect.Array:
+		return checkType(t.Elem())
+	case reflect.Struct:
+		for i := range t.NumField() {
+			if !checkType(t.Field(i).Type) {
+				return false
+			}
+		}
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,258 @@
+package mmap
+
+import "encoding/binary"
+
+// OrderedFile provides access to fixed-width integers in
+// the mapped region using the bound byte order.
+type OrderedFile struct {
+	m     *File
+	order binary.ByteOrder
+}
+
+// WithByteOrder returns an OrderedFile that reads and
+// writes integers in the mapped region using order.
+func (m *File) WithByteOrder(order binary.ByteOrder) OrderedFile {
+	return OrderedFile{m: m, order: order}
+}
+
+// BigEndian returns an OrderedFile that reads and writes
+// integers in the mapped region in big endian (network) order.
+func (m *File) BigEndian() OrderedFile {
+	return m.WithByteOrder(binary.BigEndian)
+}
+
+// LittleEndian returns an OrderedFile that reads and writes integers in the
+// mapped region in little endian order, same as the functions on File.
+func (m *File) LittleEndian() OrderedFile {
+	return m.WithByteOrder(binary.LittleEndian)
+}
+
+// ReadUint8At reads uint8 from offset.
+func (m *File) ReadUint8At(offset int64) uint8 {
+	return m.readUint8(offset)
+}
+
+// WriteUint8At writes num at offset.
+func (m *File) WriteUint8At(num uint8, offset int64) {
+	m.writeUint8(num, offset)
+}
+
+// ReadInt8At reads int8 from offset.
+func (m *File) ReadInt8At(offset int64) int8 {
+	return int8(m.readUint8(offset))
+}
+
+// WriteInt8At writes num at offset.
+func (m *File) WriteInt8At(num int8, offset int64) {
+	m.writeUint8(uint8(num), offset)
+}
+
+// ReadUint16At reads little endian uint16 from offset.
+func (m *File) ReadUint16At(offset int64) uint16 {
+	return m.readUint16(binary.LittleEndian, offset)
+}
+
+// WriteUint16At writes num at offset in little endian order.
+func (m *File) WriteUint16At(num uint16, offset int64) {
+	m.writeUint16(binary.LittleEndian, num, offset)
+}
+
+// ReadInt16At reads little endian int16 from offset.
+func (m *File) ReadInt16At(offset int64) int16 {
+	return int16(m.readUint16(binary.LittleEndian, offset))
+}
+
+// WriteInt16At writes num at offset in little endian order.
+func (m *File) WriteInt16At(num int16, offset int64) {
+	m.writeUint16(binary.LittleEndian, uint16(num), offset)
+}
+
+// ReadUint32At reads little endian uint32 from offset.
+func (m *File) ReadUint32At(offset int64) uint32 {
+	return m.readUint32(binary.LittleEndian, offset)
+}
+
+// WriteUint32At writes num at offset in little endian order.
+func (m *File) WriteUint32At(num uint32, offset int64) {
+	m.writeUint32(binary.LittleEndian, num, offset)
+}
+
+// ReadInt32At reads little endian int32 from offset.
+func (m *File) ReadInt32At(offset int64) int32 {
+	return int32(m.readUint32(binary.LittleEndian, offset))
+}
+
+// WriteInt32At writes num at offset in little endian order.
+func (m *File) WriteInt32At(num int32, offset int64) {
+	m.writeUint32(binary.LittleEndian, uint32(num), offset)
+}
+
+// ReadInt64At reads little endian int64 from offset.
+func (m *File) ReadInt64At(offset int64) int64 {
+	return int64(m.readUint64(binary.LittleEndian, offset))
+}
+
+// WriteInt64At writes num at offset in little endian order.
+func (m *File) WriteInt64At(num int64, offset int64) {
+	m.writeUint64(binary.LittleEndian, uint64(num), offset)
+}
+
+// ReadUint16At reads uint16 from offset.
+func (o OrderedFile) ReadUint16At(offset int64) uint16 {
+	return o.m.readUint16(o.order, offset)
+}
+
+// WriteUint16At writes num at offset.
+func (o OrderedFile) WriteUint16At(num uint16, offset int64) {
+	o.m.writeUint16(o.order, num, offset)
+}
+
+// ReadInt16At reads int16 from offset.
+func (o OrderedFile) ReadInt16At(offset int64) int16 {
+	return int16(o.m.readUint16(o.order, offset))
+}
+
+// WriteInt16At writes num at offset.
+func (o OrderedFile) WriteInt16At(num int16, offset int64) {
+	o.m.writeUint16(o.order, uint16(num), offset)
+}
+
+// ReadUint32At reads uint32 from offset.
+func (o OrderedFile) ReadUint32At(offset int64) uint32 {
+	return o.m.readUint32(o.order, offset)
+}
+
+// WriteUint32At writes num at offset.
+func (o OrderedFile) WriteUint32At(num uint32, offset int64) {
+	o.m.writeUint32(o.order, num, offset)
+}
+
+// ReadInt32At reads int32 from offset.
+func (o OrderedFile) ReadInt32At(offset int64) int32 {
+	return int32(o.m.readUint32(o.order, offset))
+}
+
+// WriteInt32At writes num at offset.
+func (o OrderedFile) WriteInt32At(num int32, offset int64) {
+	o.m.writeUint32(o.order, uint32(num), offset)
+}
+
+// ReadUint64At reads uint64 from offset.
+func (o OrderedFile) ReadUint64At(offset int64) uint64 {
+	return o.m.readUint64(o.order, offset)
+}
+
+// WriteUint64At writes num at offset.
+func (o OrderedFile) WriteUint64At(num uint64, offset int64) {
+	o.m.writeUint64(o.order, num, offset)
+}
+
+// ReadInt64At reads int64 from offset.
+func (o OrderedFile) ReadInt64At(offset int64) int64 {
+	return int64(o.m.readUint64(o.order, offset))
+}
+
+// WriteInt64At writes num at offset.
+func (o OrderedFile) WriteInt64At(num int64, offset int64) {
+	o.m.writeUint64(o.order, uint64(num), offset)
+}
+
+func (m *File) readUint8(offset int64) uint8 {
+	m.rlock()
+	defer m.runlock()
+	if m.recoverFaults {
+		defer faultChecks(panicOnFault())
+	}
+
+	m.boundaryChecks(offset, 1)
+	return m.data[offset]
+}
+
+func (m *File) writeUint8(num uint8, offset int64) {
+	m.mustGrow(offset, 1)
+
+	m.rlock()
+	defer m.runlock()
+	if m.recoverFaults {
+		defer faultChecks(panicOnFault())
+	}
+
+	m.writeChecks(offset, 1)
+	m.data[offset] = num
+	m.markDirty(offset, 1)
+}
+
+func (m *File) readUint16(order binary.ByteOrder, offset int64) uint16 {
+	m.rlock()
+	defer m.runlock()
+	if m.recoverFaults {
+		defer faultChecks(panicOnFault())
+	}
+
+	m.boundaryChecks(offset, 2)
+	return order.Uint16(m.data[offset : offset+2])
+}
+
+func (m *File) writeUint16(order binary.ByteOrder, num uint16, offset int64) {
+	m.mustGrow(offset, 2)
+
+	m.rlock()
+	defer m.runlock()
+	if m.recoverFaults {
+		defer faultChecks(panicOnFault())
+	}
+
+	m.writeChecks(offset, 2)
+	order.PutUint16(m.data[offset:offset+2], num)
+	m.markDirty(offset, 2)
+}
+
+func (m *File) readUint32(order binary.ByteOrder, offset int64) uint32 {
+	m.rlock()
+	defer m.runlock()
+	if m.recoverFaults {
+		defer faultChecks(panicOnFault())
+	}
+
+	m.boundaryChecks(offset, 4)
+	return order.Uint32(m.data[offset : offset+4])
+}
+
+func (m *File) writeUint32(order binary.ByteOrder, num uint32, offset int64) {
+	m.mustGrow(offset, 4)
+
+	m.rlock()
+	defer m.runlock()
+	if m.recoverFaults {
+		defer faultChecks(panicOnFault())
+	}
+
+	m.writeChecks(offset, 4)
+	order.PutUint32(m.data[offset:offset+4], num)
+	m.markDirty(offset, 4)
+}
+
+func (m *File) readUint64(order binary.ByteOrder, offset int64) uint64 {
+	m.rlock()
+	defer m.runlock()
+	if m.recoverFaults {
+		defer faultChecks(panicOnFault())
+	}
+
+	m.boundaryChecks(offset, 8)
+	return order.Uint64(m.data[offset : offset+8])
+}
+
+func (m *File) writeUint64(order binary.ByteOrder, num uint64, offset int64) {
+	m.mustGrow(offset, 8)
+
+	m.rlock()
+	defer m.runlock()
+	if m.recoverFaults {
+		defer faultChecks(panicOnFault())
+	}
+
+	m.writeChecks(offset, 8)
+	order.PutUint64(m.data[offset:offset+8], num)
+	m.markDirty(offset, 8)
+}
@@ -0,0 +1,67 @@
+package mmap
+
+import (
+	"fmt"
+	"math/bits"
+	"syscall"
+)
+
+const mapHugeShift = 26
+
+const (
+	// AdviceCold (MADV_COLD) deactivates the pages, making them
+	// more likely to be reclaimed under memory pressure.
+	AdviceCold = 20
+	// AdvicePageOut (MADV_PAGEOUT) reclaims the pages immediately.
+	AdvicePageOut = 21
+)
+
+// WithPopulate maps the memory with MAP_POPULATE flag, page tables are
+// populated (prefaulted) during mapping to avoid page faults on first access.
+func WithPopulate() Option {
+	return func(o *options) {
+		o.mapFlags |= syscall.MAP_POPULATE
+	}
+}
+
+// WithLocked maps the memory with MAP_LOCKED flag, pages of the
+// mapping are locked to RAM similar to calling Lock after mapping.
+func WithLocked() Option {
+	return func(o *options) {
+		o.mapFlags |= syscall.MAP_LOCKED
+	}
+}
+
+// WithHugeTLB maps the memory using huge pages with MAP_HUGETLB flag.
+// hugePageSize selects the size of the huge pages (e.g. 2MB or 1GB) and must
+// be a power of two, zero selects the default huge page size of the system.
+// Mapping fails if hugePageSize is invalid. The mapping is either anonymous
+// or backed by a file in hugetlbfs, in which case, the offset and the length
+// of the mapping must be multiples of the huge page size.
+//
+// Functions working with pages, such as DirtyRanges, Flush, FlushRange, Evict,
+// Prefetch, ProtectRange and Resident, use the base page size of the system
+// regardless. Hence, modifications are tracked and residency is reported per
+// base page, and the syscalls made by these functions fail with EINVAL unless
+// the ranges passed to them are aligned to the huge page size.
+func WithHugeTLB(hugePageSize int) Option {
+	return func(o *options) {
+		if hugePageSize < 0 || bits.OnesCount(uint(hugePageSize)) > 1 {
+			o.err = fmt.Errorf("invalid huge page size %d, must be a power of two", hugePageSize)
+			return
+		}
+
+		o.mapFlags |= syscall.MAP_HUGETLB
+		if hugePageSize > 0 {
+			o.mapFlags |= bits.TrailingZeros(uint(hugePageSize)) << mapHugeShift
+		}
+	}
+}
+
+// WithTransparentHugePages advises the kernel to back the mapping
+// with transparent huge pages using MADV_HUGEPAGE after mapping.
+func WithTransparentHugePages() Option {
+	return func(o *options) {
+		o.advice = append(o.advice, syscall.MADV_HUGEPAGE)
+	}
+}
@@ -0,0 +1,116 @@
+package mmap
+
+import (
+	"bytes"
+	"os"
+	"path"
+	"syscall"
+	"testing"
+)
+
+func TestMappingOptions(t *testing.T) {
+	t.Parallel()
+
+	m, err := NewAnonymousMmap(4<<20, protPage, WithPopulate(), WithNoReserve(), WithTransparentHugePages())
+	if err != nil {
+		t.Fatalf("error in mapping :: %v", err)
+	}
+	if m.flags&(syscall.MAP_POPULATE|syscall.MAP_NORESERVE) == 0 {
+		t.Fatalf("mapping flags not set: %x", m.flags)
+	}
+	m.WriteUint64At(10000000000, 3<<20)
+	if m.ReadUint64At(3<<20) != 10000000000 {
+		t.Fatalf("value read is not equal to value written")
+	}
+	if err := m.Unmap(); err != nil {
+		t.Fatalf("error in calling unmap :: %v", err)
+	}
+
+	// Locked memory is limited by RLIMIT_MEMLOCK, hence, lock a single page
+	m, err = NewAnonymousMmap(os.Getpagesize(), protPage, WithLocked())
+	if err != nil {
+		t.Fatalf("error in mapping :: %v", err)
+	}
+	if m.flags&syscall.MAP_LOCKED == 0 {
+		t.Fatalf("mapping flags not set: %x", m.flags)
+	}
+	if err := m.Unmap(); err != nil {
+		t.Fatalf("error in calling unmap :: %v", err)
+	}
+
+	testPath := path.Join(t.TempDir(), "m.txt")
+	setup(t, testPath)
+	m, err = Open(testPath, os.O_RDWR, WithPopulate())
+	if err != nil {
+		t.Fatalf("error in opening mapped file :: %v", err)
+	}
+	if m.ReadUint8At(0) != testData[0] {
+		t.Fatalf("mapped data is not equal testData")
+	}
+	if err := m.Close(); err != nil {
+		t.Fatalf("error in closing mapped file :: %v", err)
+	}
+
+	if _, err := NewAnonymousMmap(2<<20, protPage, WithHugeTLB(3<<20)); err == nil {
+		t.Fatalf("expected error in mapping with invalid huge page size")
+	}
+
+	// Huge pages are usually not reserved, mapping must either succeed or fail cleanly
+	if m, err := NewAnonymousMmap(2<<20, protPage, WithHugeTLB(2<<20)); err == nil {
+		if m.flags&syscall.MAP_HUGETLB == 0 || m.flags>>mapHugeShift != 21 {
+			t.Fatalf("huge page flags not set: %x", m.flags)
+		}
+		if err := m.Unmap(); err != nil {
+			t.Fatalf("error in calling unmap :: %v", err)
+		}
+	}
+}
+
+func TestEvictAdvice(t *testing.T) {
+	t.Parallel()
+
+	m, err := NewAnonymousMmap(4*os.Getpagesize(), protPage)
+	if err != nil {
+		t.Fatalf("error in mapping :: %v", err)
+	}
+	defer func() {
+		if err := m.Unmap(); err != nil {
+			t.Fatalf("error in calling unmap :: %v", err)
+		}
+	}()
+
+	m.WriteUint64At(1, 0)
+	for _, advice := range []int{AdviceCold, AdvicePageOut} {
+		// MADV_COLD and MADV_PAGEOUT need linux 5.4+
+		if err := m.Evict(0, m.length, advice); err != nil && err != syscall.EINVAL {
+			t.Fatalf("error in calling evict :: %v", err)
+		}
+	}
+	if m.ReadUint64At(0) != 1 {
+		t.Fatalf("data lost after deactivating pages")
+	}
+}
+
+func TestAnonymousRingBuffer(t *testing.T) {
+	t.Parallel()
+
+	r, err := NewAnonymousRingBuffer(2 * os.Getpagesize())
+	if err != nil {
+		t.Fatalf("error in creating ring buffer :: %v", err)
+	}
+
+	data := bytes.Repeat([]byte("0123456789"), os.Getpagesize()/5)
+	for i := range 10 {
+		if n, err := r.Write(data[:len(data)-i]); err != nil || n != len(data)-i {
+			t.Fatalf("error in writing to ring buffer :: %v", err)
+		}
+		if !bytes.Equal(r.Readable(), data[:len(data)-i]) {
+			t.Fatalf("data read is not equal to data written")
+		}
+		r.Consume(len(data) - i)
+	}
+
+	if err := r.Close(); err != nil {
+		t.Fatalf("error in closing ring buffer :: %v", err)
+	}
+}
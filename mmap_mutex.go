@@ -0,0 +1,292 @@
+package mmap
+
+import (
+	"errors"
+	"math"
+	"os"
+	"syscall"
+	"time"
+)
+
+// Layout of the uint32 word of SharedMutex and SharedRWMutex. The low bits hold
+// the PID of the owner, or the number of readers if a SharedRWMutex is read
+// locked. lockWaiters is set if any goroutine or process is blocked on the word.
+const (
+	lockWaiters = 1 << 31
+	lockWriter  = 1 << 30
+	lockMask    = lockWriter - 1
+)
+
+// ownerCheckInterval is the interval at which a blocked
+// lock checks whether the owner process is still alive.
+const ownerCheckInterval = 100 * time.Millisecond
+
+// ownerDead returns true if no process with the given pid exists.
+func ownerDead(pid uint32) bool {
+	return pid != 0 && errors.Is(syscall.Kill(int(pid), 0), syscall.ESRCH)
+}
+
+// SharedMutex is a mutual exclusion lock on a uint32 word in the mapped region
+// that can be shared by multiple processes, unlike sync.Mutex. The word holds
+// the PID of the owner process, a zero word is an unlocked mutex. If the owner
+// process dies while holding the lock, the next Lock takes over the lock and
+// returns ErrOwnerDead, in which case, the data protected by the lock may be
+// inconsistent. Like sync.Mutex, SharedMutex is not associated with a goroutine
+// and can be unlocked by any goroutine of the owner process. SharedMutex blocks
+// using futex on linux and polls the word on other platforms.
+//
+// The owner is identified only by its PID, which is checked using kill(pid, 0).
+// All the processes sharing the mutex must be in the same PID namespace, as a
+// PID from another namespace may refer to a different process or none at all,
+// in which case, the lock is taken over from a live owner. If the PID of a dead
+// owner is reused by a new process before the lock is taken over, the lock is
+// not recovered until the new process exits.
+type SharedMutex struct {
+	m      *File
+	offset int64
+	pid    uint32
+}
+
+// NewSharedMutex returns SharedMutex on the uint32 word at offset. Offset
+// must be aligned to 4 bytes in memory, functions of SharedMutex panic
+// with ErrUnalignedOffset or ErrIndexOutOfBound otherwise.
+func NewSharedMutex(m *File, offset int64) *SharedMutex {
+	return &SharedMutex{m: m, offset: offset, pid: uint32(os.Getpid())}
+}
+
+// Lock locks the mutex, blocking until the lock is available. Lock returns
+// ErrOwnerDead if the lock is acquired from a process that died holding it,
+// the mutex is locked by the calling process in this case as well.
+func (mu *SharedMutex) Lock() error {
+	if mu.m.CompareAndSwapUint32At(0, mu.pid, mu.offset) {
+		return nil
+	}
+
+	var expired bool
+	for {
+		v := mu.m.AtomicLoadUint32At(mu.offset)
+		switch {
+		case v == 0:
+			if mu.m.CompareAndSwapUint32At(0, mu.pid|lockWaiters, mu.offset) {
+				return nil
+			}
+			continue
+		case expired && ownerDead(v&lockMask):
+			if mu.m.CompareAndSwapUint32At(v, mu.pid|lockWaiters, mu.offset) {
+				return ErrOwnerDead
+			}
+			continue
+		case v&lockWaiters == 0:
+			if !mu.m.CompareAndSwapUint32At(v, v|lockWaiters, mu.offset) {
+				continue
+			}
+		}
+
+		err := mu.m.WaitUint32At(v|lockWaiters, mu.offset, ownerCheckInterval)
+		if expired = errors.Is(err, os.ErrDeadlineExceeded); err != nil && !expired {
+			return err
+		}
+	}
+}
+
+// TryLock tries to lock the mutex without blocking and reports whether it
+// succeeded. TryLock returns ErrOwnerDead as documented for Lock.
+func (mu *SharedMutex) TryLock() (bool, error) {
+	for {
+		v := mu.m.AtomicLoadUint32At(mu.offset)
+		switch {
+		case v == 0:
+			if mu.m.CompareAndSwapUint32At(0, mu.pid, mu.offset) {
+				return true, nil
+			}
+		case ownerDead(v & lockMask):
+			if mu.m.CompareAndSwapUint32At(v, mu.pid|v&lockWaiters, mu.offset) {
+				return true, ErrOwnerDead
+			}
+		default:
+			return false, nil
+		}
+	}
+}
+
+// Unlock unlocks the mutex and wakes up a waiter, if any.
+// It is a run-time error if the mutex is not locked.
+func (mu *SharedMutex) Unlock() {
+	for {
+		v := mu.m.AtomicLoadUint32At(mu.offset)
+		if v&lockMask == 0 {
+			panic("mmap: unlock of unlocked SharedMutex")
+		}
+
+		if mu.m.CompareAndSwapUint32At(v, 0, mu.offset) {
+			if v&lockWaiters != 0 {
+				_, _ = mu.m.WakeUint32At(mu.offset, 1)
+			}
+			return
+		}
+	}
+}
+
+// SharedRWMutex is a reader/writer mutual exclusion lock on a uint32 word in
+// the mapped region that can be shared by multiple processes. If a writer
+// process dies while holding the lock, the lock is taken over as documented
+// for SharedMutex, subject to the same limitations. Readers are not tracked,
+// hence, the lock cannot be recovered if a reader process dies while holding
+// it. Writers may starve while readers keep holding the lock.
+type SharedRWMutex struct {
+	m      *File
+	offset int64
+	pid    uint32
+}
+
+// NewSharedRWMutex returns SharedRWMutex on the uint32 word at offset.
+// Offset must be aligned to 4 bytes in memory.
+func NewSharedRWMutex(m *File, offset int64) *SharedRWMutex {
+	return &SharedRWMutex{m: m, offset: offset, pid: uint32(os.Getpid())}
+}
+
+// RLock locks the mutex for reading, blocking while it is locked for writing.
+// RLock returns ErrOwnerDead if the lock is acquired from a writer process that
+// died holding it, the mutex is locked for reading in this case as well.
+func (rw *SharedRWMutex) RLock() error {
+	var expired bool
+	for {
+		v := rw.m.AtomicLoadUint32At(rw.offset)
+		switch {
+		case v&lockWriter == 0:
+			if rw.m.CompareAndSwapUint32At(v, v+1, rw.offset) {
+				return nil
+			}
+			continue
+		case expired && ownerDead(v&lockMask):
+			if rw.m.CompareAndSwapUint32At(v, 1|lockWaiters, rw.offset) {
+				_, _ = rw.m.WakeUint32At(rw.offset, math.MaxInt32)
+				return ErrOwnerDead
+			}
+			continue
+		case v&lockWaiters == 0:
+			if !rw.m.CompareAndSwapUint32At(v, v|lockWaiters, rw.offset) {
+				continue
+			}
+		}
+
+		err := rw.m.WaitUint32At(v|lockWaiters, rw.offset, ownerCheckInterval)
+		if expired = errors.Is(err, os.ErrDeadlineExceeded); err != nil && !expired {
+			return err
+		}
+	}
+}
+
+// RUnlock undoes a single RLock call. It is a run-time
+// error if the mutex is not locked for reading.
+func (rw *SharedRWMutex) RUnlock() {
+	for {
+		v := rw.m.AtomicLoadUint32At(rw.offset)
+		if v&lockWriter != 0 || v&lockMask == 0 {
+			panic("mmap: RUnlock of unlocked SharedRWMutex")
+		}
+
+		// The last reader clears lockWaiters and wakes up all the waiters.
+		next := v - 1
+		if next&lockMask == 0 {
+			next = 0
+		}
+		if rw.m.CompareAndSwapUint32At(v, next, rw.offset) {
+			if next == 0 && v&lockWaiters != 0 {
+				_, _ = rw.m.WakeUint32At(rw.offset, math.MaxInt32)
+			}
+			return
+		}
+	}
+}
+
+// Lock locks the mutex for writing, blocking while it is locked for reading
+// or writing. Lock returns ErrOwnerDead as documented for SharedMutex.
+func (rw *SharedRWMutex) Lock() error {
+	var expired bool
+	for {
+		v := rw.m.AtomicLoadUint32At(rw.offset)
+		switch {
+		case v&^lockWaiters == 0:
+			if rw.m.CompareAndSwapUint32At(v, lockWriter|rw.pid|v, rw.offset) {
+				return nil
+			}
+			continue
+		case expired && v&lockWriter != 0 && ownerDead(v&lockMask):
+			if rw.m.CompareAndSwapUint32At(v, lockWriter|rw.pid|lockWaiters, rw.offset) {
+				return ErrOwnerDead
+			}
+			continue
+		case v&lockWaiters == 0:
+			if !rw.m.CompareAndSwapUint32At(v, v|lockWaiters, rw.offset) {
+				continue
+			}
+		}
+
+		err := rw.m.WaitUint32At(v|lockWaiters, rw.offset, ownerCheckInterval)
+		if expired = errors.Is(err, os.ErrDeadlineExceeded); err != nil && !expired {
+			return err
+		}
+	}
+}
+
+// Unlock unlocks the mutex for writing and wakes up all the waiters.
+// It is a run-time error if the mutex is not locked for writing.
+func (rw *SharedRWMutex) Unlock() {
+	for {
+		v := rw.m.AtomicLoadUint32At(rw.offset)
+		if v&lockWriter == 0 {
+			panic("mmap: unlock of unlocked SharedRWMutex")
+		}
+
+		if rw.m.CompareAndSwapUint32At(v, 0, rw.offset) {
+			if v&lockWaiters != 0 {
+				_, _ = rw.m.WakeUint32At(rw.offset, math.MaxInt32)
+			}
+			return
+		}
+	}
+}
+
+// SharedCond is a condition variable on a uint32 word in the mapped region that
+// can be shared by multiple processes. The word holds a sequence number which
+// is incremented by Signal and Broadcast, waiters block until it changes.
+type SharedCond struct {
+	m      *File
+	offset int64
+}
+
+// NewSharedCond returns SharedCond on the uint32 word at offset.
+// Offset must be aligned to 4 bytes in memory.
+func NewSharedCond(m *File, offset int64) *SharedCond {
+	return &SharedCond{m: m, offset: offset}
+}
+
+// Wait unlocks mu, which must be locked by the caller, blocks until woken up by
+// Signal or Broadcast, or until timeout elapses, and locks mu again before
+// returning. Negative timeout waits indefinitely. Wait returns ErrOwnerDead if
+// locking mu returns it and os.ErrDeadlineExceeded if timeout elapses. Wait
+// may return spuriously, hence, the caller must check the condition in a loop.
+func (c *SharedCond) Wait(mu *SharedMutex, timeout time.Duration) error {
+	seq := c.m.AtomicLoadUint32At(c.offset)
+	mu.Unlock()
+
+	err := c.m.WaitUint32At(seq, c.offset, timeout)
+	if errLock := mu.Lock(); errLock != nil {
+		return errLock
+	}
+
+	return err
+}
+
+// Signal wakes up one waiter, if any.
+func (c *SharedCond) Signal() {
+	c.m.AtomicAddUint32At(1, c.offset)
+	_, _ = c.m.WakeUint32At(c.offset, 1)
+}
+
+// Broadcast wakes up all the waiters.
+func (c *SharedCond) Broadcast() {
+	c.m.AtomicAddUint32At(1, c.offset)
+	_, _ = c.m.WakeUint32At(c.offset, math.MaxInt32)
+}
@@ -0,0 +1,168 @@
+package mmap
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// Option configures the memory mapping. Options WithOffset, WithLength
+// and WithPerm are only used by Open and Create.
+type Option func(*options)
+
+type options struct {
+	offset int64
+	length int
+	perm   os.FileMode
+	growth GrowthPolicy
+
+	concurrent    bool
+	recoverFaults bool
+	mapFlags      int
+	advice        []int
+
+	// addr is the address of a reserved region where the mapping is
+	// placed using MAP_FIXED, the kernel chooses the address if it is 0.
+	addr uintptr
+
+	// err is set by an option with an invalid argument.
+	err error
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{perm: 0o644}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
+
+// WithOffset sets the offset in the file from where the mapping starts.
+// Offset need not be page aligned, default is 0.
+func WithOffset(offset int64) Option {
+	return func(o *options) {
+		o.offset = offset
+	}
+}
+
+// WithLength sets the length of the mapped region. By default,
+// the file is mapped from the offset until the end of the file.
+func WithLength(length int) Option {
+	return func(o *options) {
+		o.length = length
+	}
+}
+
+// WithNoReserve maps the memory with MAP_NORESERVE flag,
+// swap space is not reserved for the mapping.
+func WithNoReserve() Option {
+	return func(o *options) {
+		o.mapFlags |= syscall.MAP_NORESERVE
+	}
+}
+
+// WithPerm sets the permission bits used when the file is created, default is 0644.
+func WithPerm(perm os.FileMode) Option {
+	return func(o *options) {
+		o.perm = perm
+	}
+}
+
+// Open opens the named file using the given mode (os.O_RDONLY, os.O_RDWR etc.)
+// and maps it into memory. The protection of the mapped region is derived
+// from the mode, hence, mode must permit reading the file. If the file is
+// opened for writing and is smaller than the region to be mapped, the file
+// is extended to fit the mapped region. The returned File owns the opened
+// file and must be closed using Close.
+func Open(path string, mode int, opts ...Option) (*File, error) {
+	o := newOptions(opts)
+	if o.err != nil {
+		return nil, o.err
+	}
+
+	f, err := os.OpenFile(path, mode, o.perm)
+	if err != nil {
+		return nil, err
+	}
+
+	return mapFile(f, mode, o)
+}
+
+// Create creates or truncates the named file, sizes it to the given size
+// and maps it into memory for reading and writing. The returned File owns
+// the created file and must be closed using Close.
+func Create(path string, size int64, opts ...Option) (*File, error) {
+	o := newOptions(opts)
+	if o.err != nil {
+		return nil, o.err
+	}
+
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, o.perm)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := f.Truncate(size); err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+
+	return mapFile(f, os.O_RDWR, o)
+}
+
+// mapFile maps f according to the given options. File f is closed if mapping fails.
+func mapFile(f *os.File, mode int, o *options) (*File, error) {
+	m, err := mapFileWithOptions(f, mode, o)
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+
+	m.ownsFile = true
+	return m, nil
+}
+
+func mapFileWithOptions(f *os.File, mode int, o *options) (*File, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	prot := syscall.PROT_READ
+	writable := mode&(os.O_WRONLY|os.O_RDWR) != 0
+	if writable {
+		prot |= syscall.PROT_WRITE
+	}
+
+	length := int64(o.length)
+	if length == 0 {
+		length = info.Size() - o.offset
+	}
+	if o.offset < 0 || length <= 0 {
+		return nil, fmt.Errorf("%w: offset %d, length %d, file size %d",
+			ErrIndexOutOfBound, o.offset, length, info.Size())
+	}
+
+	if end := o.offset + length; end > info.Size() {
+		if !writable {
+			return nil, fmt.Errorf("%w: region end %d beyond file size %d",
+				ErrIndexOutOfBound, end, info.Size())
+		}
+		if err := f.Truncate(end); err != nil {
+			return nil, err
+		}
+	}
+
+	// mmap requires the offset in file to be a multiple of page size.
+	pageOffset := o.offset % int64(pageSize)
+	m, err := newMmap(f, o.offset-pageOffset, int(length+pageOffset), prot, syscall.MAP_SHARED, o)
+	if err != nil {
+		return nil, err
+	}
+
+	m.data = m.mapping[pageOffset:]
+	m.length = length
+	m.mappedLength = length
+	return m, nil
+}
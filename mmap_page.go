@@ -1,39 +1,142 @@
 package mmap
 
 import (
+	"os"
 	"syscall"
 	"unsafe"
 )
 
+var pageSize = os.Getpagesize()
+
 // Advise provides hints to kernel regarding the use of memory mapped region.
 func (m *File) Advise(advice int) error {
-	_, _, err := syscall.Syscall(syscall.SYS_MADVISE,
-		uintptr(unsafe.Pointer(&m.data[0])), uintptr(m.length), uintptr(advice))
-	if err != 0 {
-		return err
-	}
+	m.rlock()
+	defer m.runlock()
 
-	return nil
+	return m.pageSyscall(syscall.SYS_MADVISE, 0, m.length, advice)
+}
+
+// AdviseRange provides hints to kernel regarding the use of the pages of memory
+// mapped region overlapping length bytes starting at given offset.
+func (m *File) AdviseRange(offset, length int64, advice int) error {
+	m.rlock()
+	defer m.runlock()
+
+	return m.pageSyscall(syscall.SYS_MADVISE, offset, length, advice)
 }
 
 // Lock locks all the mapped memory to RAM, preventing the pages from swapping out.
 func (m *File) Lock() error {
-	_, _, err := syscall.Syscall(syscall.SYS_MLOCK,
-		uintptr(unsafe.Pointer(&m.data[0])), uintptr(m.length), 0)
-	if err != 0 {
-		return err
-	}
+	m.rlock()
+	defer m.runlock()
 
-	return nil
+	return m.pageSyscall(syscall.SYS_MLOCK, 0, m.length, 0)
+}
+
+// LockRange locks the pages of mapped memory overlapping length bytes starting
+// at given offset to RAM, preventing the pages from swapping out.
+func (m *File) LockRange(offset, length int64) error {
+	m.rlock()
+	defer m.runlock()
+
+	return m.pageSyscall(syscall.SYS_MLOCK, offset, length, 0)
 }
 
 // Unlock unlocks the mapped memory from RAM, enabling swapping out of RAM if required.
 func (m *File) Unlock() error {
-	_, _, err := syscall.Syscall(syscall.SYS_MUNLOCK,
-		uintptr(unsafe.Pointer(&m.data[0])), uintptr(m.length), 0)
-	if err != 0 {
+	m.rlock()
+	defer m.runlock()
+
+	return m.pageSyscall(syscall.SYS_MUNLOCK, 0, m.length, 0)
+}
+
+// UnlockRange unlocks the pages of mapped memory overlapping length bytes
+// starting at given offset from RAM, enabling swapping out of RAM if required.
+func (m *File) UnlockRange(offset, length int64) error {
+	m.rlock()
+	defer m.runlock()
+
+	return m.pageSyscall(syscall.SYS_MUNLOCK, offset, length, 0)
+}
+
+// Resident reports for each page of the mapped region overlapping length
+// bytes starting at given offset, whether the page is resident in memory.
+func (m *File) Resident(offset, length int64) ([]bool, error) {
+	m.rlock()
+	defer m.runlock()
+
+	vec, err := m.mincore(offset, length)
+	if err != nil {
+		return nil, err
+	}
+
+	resident := make([]bool, len(vec))
+	for i, v := range vec {
+		resident[i] = v&1 != 0
+	}
+	return resident, nil
+}
+
+// ResidentRatio returns the fraction of the pages of the mapped region
+// overlapping length bytes starting at given offset that are resident in memory.
+func (m *File) ResidentRatio(offset, length int64) (float64, error) {
+	m.rlock()
+	defer m.runlock()
+
+	vec, err := m.mincore(offset, length)
+	if err != nil || len(vec) == 0 {
+		return 0, err
+	}
+
+	count := 0
+	for _, v := range vec {
+		count += int(v & 1)
+	}
+	return float64(count) / float64(len(vec)), nil
+}
+
+// mincore returns the residency vector, as returned by mincore syscall, for the
+// pages overlapping length bytes starting at given offset. Caller must hold the
+// read lock.
+func (m *File) mincore(offset, length int64) ([]byte, error) {
+	addr, size, err := m.pageRange(offset, length)
+	if err != nil || size == 0 {
+		return nil, err
+	}
+
+	vec := make([]byte, (int(size)+pageSize-1)/pageSize)
+	_, _, errno := syscall.Syscall(syscall.SYS_MINCORE, addr, size, uintptr(unsafe.Pointer(&vec[0])))
+	if errno != 0 {
+		return nil, errno
+	}
+
+	return vec, nil
+}
+
+// pageRange returns the start address and length of the page aligned memory
+// region that contains length bytes of mapped region starting at given offset.
+func (m *File) pageRange(offset, length int64) (uintptr, uintptr, error) {
+	if err := m.checkBounds(offset, length); err != nil {
+		return 0, 0, err
+	}
+
+	addr := addrOf(m.data) + uintptr(offset)
+	start := addr &^ uintptr(pageSize-1)
+	return start, addr + uintptr(length) - start, nil
+}
+
+// pageSyscall makes the syscall trap on the page aligned memory
+// region containing length bytes starting at given offset.
+func (m *File) pageSyscall(trap uintptr, offset, length int64, arg int) error {
+	addr, size, err := m.pageRange(offset, length)
+	if err != nil {
 		return err
 	}
 
+	_, _, errno := syscall.Syscall(trap, addr, size, uintptr(arg))
+	if errno != 0 {
+		return errno
+	}
+
 	return nil
 }
@@ -0,0 +1,102 @@
+package mmap
+
+import (
+	"runtime"
+	"sync"
+	"syscall"
+)
+
+// prefetchChunk is the number of bytes prefetched by a goroutine at a time.
+const prefetchChunk = 4 << 20
+
+// Prefetch loads the pages of the mapped region overlapping length bytes starting at
+// given offset into memory. The region is split into chunks that are prefetched by
+// up to concurrency goroutines (GOMAXPROCS if concurrency <= 0), each chunk is first
+// advised with MADV_WILLNEED and then its pages are touched to fault them in. If
+// progress is not nil, it is called after each chunk with the number of bytes
+// prefetched so far and the total, calls to progress are never concurrent.
+// Prefetch returns the first error encountered, if any.
+func (m *File) Prefetch(offset, length int64, concurrency int, progress func(done, total int64)) error {
+	m.rlock()
+	defer m.runlock()
+
+	if err := m.checkBounds(offset, length); err != nil {
+		return err
+	}
+	if concurrency <= 0 {
+		concurrency = runtime.GOMAXPROCS(0)
+	}
+
+	chunks := make(chan Range)
+	go func() {
+		defer close(chunks)
+		for start := offset; start < offset+length; start += prefetchChunk {
+			chunks <- Range{Offset: start, Length: min(prefetchChunk, offset+length-start)}
+		}
+	}()
+
+	var mu sync.Mutex
+	var done int64
+	var firstErr error
+	var wg sync.WaitGroup
+	for range min(concurrency, int((length+prefetchChunk-1)/prefetchChunk)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for r := range chunks {
+				err := m.prefetch(r)
+
+				mu.Lock()
+				if err != nil && firstErr == nil {
+					firstErr = err
+				}
+				done += r.Length
+				if progress != nil && firstErr == nil {
+					progress(done, length)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	wg.Wait()
+	return firstErr
+}
+
+// prefetch advises and touches the pages of the given range. Caller must hold the read lock.
+func (m *File) prefetch(r Range) error {
+	if err := m.pageSyscall(syscall.SYS_MADVISE, r.Offset, r.Length, syscall.MADV_WILLNEED); err != nil {
+		return err
+	}
+
+	return m.checkFault(func() {
+		var sum byte
+		for i := r.Offset; i < r.Offset+r.Length; i += int64(pageSize) {
+			sum += m.data[i]
+		}
+		sum += m.data[r.Offset+r.Length-1]
+		runtime.KeepAlive(sum)
+	})
+}
+
+// Evict drops the pages of the mapped region overlapping length bytes starting at
+// given offset from memory using the given advice, such as MADV_DONTNEED (or
+// AdviceCold and AdvicePageOut on linux). For shared file mappings, the pages are
+// flushed to disk before eviction. Note that evicting pages of anonymous and private
+// mappings with MADV_DONTNEED discards any modifications made to these pages.
+func (m *File) Evict(offset, length int64, advice int) error {
+	m.rlock()
+	defer m.runlock()
+
+	if err := m.checkBounds(offset, length); err != nil || length <= 0 {
+		return err
+	}
+
+	if m.fileBacked() {
+		if err := m.flushRange(offset, length, syscall.MS_SYNC); err != nil {
+			return err
+		}
+	}
+
+	return m.pageSyscall(syscall.SYS_MADVISE, offset, length, advice)
+}
@@ -0,0 +1,108 @@
+package mmap
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// Prefix is the encoding of the length header written before prefixed data.
+type Prefix int
+
+const (
+	// PrefixUvarint encodes the length as an unsigned varint.
+	PrefixUvarint Prefix = iota
+	// PrefixUint32 encodes the length as a little endian uint32.
+	PrefixUint32
+)
+
+// WriteBytesPrefixedAt writes the length of src encoded as per prefix followed by
+// src at offset and returns the number of bytes written. Both the length header and
+// src must fit in the mapped region, no partial writes are done.
+func (m *File) WriteBytesPrefixedAt(src []byte, offset int64, prefix Prefix) int {
+	return writePrefixed(m, src, offset, prefix)
+}
+
+// ReadBytesPrefixedAt reads bytes written using WriteBytesPrefixedAt at offset and
+// returns a copy of the bytes along with the number of bytes consumed including the
+// length header, hence, the next record starts at offset plus the bytes consumed.
+func (m *File) ReadBytesPrefixedAt(offset int64, prefix Prefix) ([]byte, int) {
+	m.rlock()
+	defer m.runlock()
+	if m.recoverFaults {
+		defer faultChecks(panicOnFault())
+	}
+
+	start, end := m.prefixedBounds(offset, prefix)
+	return append([]byte(nil), m.data[start:end]...), int(end - offset)
+}
+
+// WriteStringPrefixedAt writes the length of src encoded as per prefix followed
+// by src at offset and returns the number of bytes written. Both the length header
+// and src must fit in the mapped region, no partial writes are done.
+func (m *File) WriteStringPrefixedAt(src string, offset int64, prefix Prefix) int {
+	return writePrefixed(m, src, offset, prefix)
+}
+
+// ReadStringPrefixedAt reads string written using WriteStringPrefixedAt at offset and
+// returns the string along with the number of bytes consumed including the length header.
+func (m *File) ReadStringPrefixedAt(offset int64, prefix Prefix) (string, int) {
+	m.rlock()
+	defer m.runlock()
+	if m.recoverFaults {
+		defer faultChecks(panicOnFault())
+	}
+
+	start, end := m.prefixedBounds(offset, prefix)
+	return string(m.data[start:end]), int(end - offset)
+}
+
+func writePrefixed[S []byte | string](m *File, src S, offset int64, prefix Prefix) int {
+	var buf [binary.MaxVarintLen64]byte
+	header := buf[:0]
+	if prefix == PrefixUint32 {
+		if uint64(len(src)) > math.MaxUint32 {
+			panic(ErrIndexOutOfBound)
+		}
+		header = binary.LittleEndian.AppendUint32(header, uint32(len(src)))
+	} else {
+		header = binary.AppendUvarint(header, uint64(len(src)))
+	}
+
+	total := int64(len(header) + len(src))
+	m.mustGrow(offset, total)
+
+	m.rlock()
+	defer m.runlock()
+	if m.recoverFaults {
+		defer faultChecks(panicOnFault())
+	}
+
+	m.writeChecks(offset, total)
+	n := copy(m.data[offset:m.length], header)
+	copy(m.data[offset+int64(n):], src)
+	m.markDirty(offset, total)
+	return int(total)
+}
+
+// prefixedBounds decodes the length header at offset and returns the
+// start and end offset of the data. Caller must hold the read lock.
+func (m *File) prefixedBounds(offset int64, prefix Prefix) (int64, int64) {
+	var length uint64
+	var headerLength int64
+	if prefix == PrefixUint32 {
+		m.boundaryChecks(offset, 4)
+		length = uint64(binary.LittleEndian.Uint32(m.data[offset : offset+4]))
+		headerLength = 4
+	} else {
+		var n int
+		length, n = m.uvarint(offset)
+		headerLength = int64(n)
+	}
+
+	start := offset + headerLength
+	if length > uint64(m.length-start) {
+		panic(ErrIndexOutOfBound)
+	}
+
+	return start, start + int64(length)
+}
@@ -0,0 +1,120 @@
+package mmap
+
+import (
+	"math/bits"
+	"syscall"
+)
+
+// Protect changes the protection of the whole mapped region to prot, e.g.
+// from PROT_READ|PROT_WRITE while loading data to PROT_READ while serving.
+// Write functions on File panic with ErrReadOnly (Try functions return it)
+// instead of crashing with SIGSEGV when writing to pages without PROT_WRITE.
+func (m *File) Protect(prot int) error {
+	m.lock()
+	defer m.unlock()
+
+	if m.data == nil {
+		return ErrUnmappedMemory
+	}
+	if err := m.protect(0, numPages(len(m.mapping))-1, prot); err != nil {
+		return err
+	}
+
+	m.prot = prot
+	m.mixedProt = false
+	return nil
+}
+
+// ProtectRange changes the protection of the pages of the mapped region
+// overlapping length bytes starting at given offset to prot. A mapping with
+// protection changed for only a part of it cannot be resized until the
+// protection of the whole mapped region is changed using Protect. ProtectRange
+// does nothing if length is zero.
+func (m *File) ProtectRange(offset, length int64, prot int) error {
+	m.lock()
+	defer m.unlock()
+
+	if err := m.checkBounds(offset, length); err != nil || length == 0 {
+		return err
+	}
+
+	first, last := m.pagesOf(offset, length)
+	if err := m.protect(first, last, prot); err != nil {
+		return err
+	}
+
+	if first == 0 && last == numPages(len(m.mapping))-1 {
+		m.prot = prot
+		m.mixedProt = false
+	} else {
+		m.mixedProt = true
+	}
+	return nil
+}
+
+// protect calls mprotect on the pages of the mapping from first to last (both
+// inclusive) and updates the read only pages. Caller must hold the write lock.
+func (m *File) protect(first, last int, prot int) error {
+	start := addrOf(m.mapping) + uintptr(first*pageSize)
+	size := uintptr((last - first + 1) * pageSize)
+	if _, _, errno := syscall.Syscall(syscall.SYS_MPROTECT, start, size, uintptr(prot)); errno != 0 {
+		return errno
+	}
+
+	if prot&syscall.PROT_WRITE == 0 {
+		m.readOnly.add(first, last)
+	} else {
+		m.readOnly.remove(first, last)
+	}
+	m.readOnlyPages = m.readOnly.count()
+	return nil
+}
+
+// writeChecks panics if numBytes cannot be written in the mapped
+// file starting at given offset, see boundaryChecks and readOnlyChecks.
+func (m *File) writeChecks(offset, numBytes int64) {
+	m.boundaryChecks(offset, numBytes)
+	m.readOnlyChecks(offset, numBytes)
+}
+
+// readOnlyChecks panics with ErrReadOnly if any of the pages overlapping
+// numBytes starting at given offset is not writable. The range must
+// already be validated using boundaryChecks.
+func (m *File) readOnlyChecks(offset, numBytes int64) {
+	if err := m.checkReadOnly(offset, numBytes); err != nil {
+		panic(err)
+	}
+}
+
+// checkReadOnly is same as readOnlyChecks except that it returns the error.
+func (m *File) checkReadOnly(offset, numBytes int64) error {
+	if m.readOnlyPages == 0 || numBytes <= 0 {
+		return nil
+	}
+
+	if first, last := m.pagesOf(offset, numBytes); m.readOnly.containsAny(first, last) {
+		return ErrReadOnly
+	}
+	return nil
+}
+
+// containsAny returns true if any page from first to last (both inclusive) is in the set.
+func (s pageSet) containsAny(first, last int) bool {
+	for i := first / 64; i <= last/64; i++ {
+		if s.load(i)&wordMask(i, first, last) != 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// count returns the number of pages in the set.
+func (s pageSet) count() int {
+	n := 0
+	for i := range s {
+		n += bits.OnesCount64(s.load(i))
+	}
+
+	return n
+}
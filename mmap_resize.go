@@ -0,0 +1,146 @@
+package mmap
+
+import (
+	"fmt"
+	"syscall"
+)
+
+// GrowthPolicy returns the new length of a mapping given its current
+// length and the minimum length required to fit a write past the end.
+type GrowthPolicy func(current, required int64) int64
+
+// WithGrowthPolicy enables automatic growth of the mapping when data is written
+// past the end of the mapped region, the new length is decided by the policy.
+func WithGrowthPolicy(policy GrowthPolicy) Option {
+	return func(o *options) {
+		o.growth = policy
+	}
+}
+
+// GrowDoubling returns a growth policy that doubles the length
+// of the mapping until it fits the required length.
+func GrowDoubling() GrowthPolicy {
+	return func(current, required int64) int64 {
+		newLength := max(current, 1)
+		for newLength < required {
+			newLength *= 2
+		}
+		return newLength
+	}
+}
+
+// GrowByChunk returns a growth policy that extends the length of the mapping
+// in multiples of chunk bytes until it fits the required length. chunk must
+// be positive.
+func GrowByChunk(chunk int64) GrowthPolicy {
+	return func(current, required int64) int64 {
+		chunks := (required - current + chunk - 1) / chunk
+		return current + chunks*chunk
+	}
+}
+
+// Resize changes the length of the mapped region to newLength. For mappings
+// backed by a file, the file is extended if it is smaller than the new mapped
+// region, the file is never shrunk. On linux, the mapping is resized using
+// mremap and may move to a different address, on other platforms the file is
+// mapped again. Private file mappings, shared anonymous mappings and mappings
+// with protection changed using ProtectRange for a part of it cannot be
+// resized. Resize fails with ErrMappingInUse if any View of the mapping is
+// not yet released. The mapping remains unchanged if an error is returned.
+// Resize must not be called concurrently with other functions on File
+// unless File is created using WithConcurrentAccess option.
+func (m *File) Resize(newLength int64) error {
+	m.lock()
+	defer m.unlock()
+
+	return m.resize(newLength)
+}
+
+// Grow extends the length of the mapped region by delta bytes.
+// See Resize for more details.
+func (m *File) Grow(delta int64) error {
+	m.lock()
+	defer m.unlock()
+
+	return m.resize(m.mappedLength + delta)
+}
+
+func (m *File) resize(newLength int64) error {
+	if m.data == nil {
+		return ErrUnmappedMemory
+	}
+	if m.views.Load() > 0 {
+		return ErrMappingInUse
+	}
+	if m.mixedProt {
+		return ErrNotResizable
+	}
+	if newLength <= 0 {
+		return fmt.Errorf("%w: invalid length %d", ErrIndexOutOfBound, newLength)
+	}
+
+	pageOffset := m.pageOffset()
+	switch {
+	case m.fileBacked():
+		info, err := m.file.Stat()
+		if err != nil {
+			return err
+		}
+		if end := m.offset + pageOffset + newLength; end > info.Size() {
+			if err := m.file.Truncate(end); err != nil {
+				return err
+			}
+		}
+	case m.flags&syscall.MAP_SHARED != 0 || m.flags&syscall.MAP_ANON == 0:
+		return ErrNotResizable
+	}
+
+	mapping, err := m.remap(int(pageOffset + newLength))
+	if err != nil {
+		return err
+	}
+
+	oldPages, newPages := numPages(len(m.mapping)), numPages(len(mapping))
+	m.dirty = m.dirty.resized(newPages)
+	m.readOnly = m.readOnly.resized(newPages)
+	if m.prot&syscall.PROT_WRITE == 0 && newPages > oldPages {
+		m.readOnly.add(oldPages, newPages-1)
+	}
+	m.readOnlyPages = m.readOnly.count()
+	m.mapping = mapping
+	m.data = mapping[pageOffset:]
+	m.length = newLength
+	m.mappedLength = newLength
+	return nil
+}
+
+// autoGrow extends the mapping as per the growth policy, if any, so that
+// numBytes can be written starting at the given offset. autoGrow must
+// be called without holding any lock on m.
+func (m *File) autoGrow(offset, numBytes int64) error {
+	if m.growth == nil || offset < 0 {
+		return nil
+	}
+
+	required := offset + numBytes
+	m.rlock()
+	fits := m.data == nil || required <= m.length
+	m.runlock()
+	if fits {
+		return nil
+	}
+
+	m.lock()
+	defer m.unlock()
+
+	if m.data == nil || required <= m.length {
+		return nil
+	}
+
+	// Required bytes may already be mapped but not accessible, see Refresh.
+	newLength := m.mappedLength
+	if required > newLength {
+		newLength = max(m.growth(m.mappedLength, required), required)
+	}
+	return m.resize(newLength)
+}
@@ -0,0 +1,295 @@
+package mmap
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"syscall"
+)
+
+// Layout of the header page of a ring buffer.
+const (
+	ringHeadOffset     = 0
+	ringTailOffset     = 8
+	ringCapacityOffset = 16
+)
+
+// RingBuffer is a single producer single consumer byte queue in a memory
+// mapped region, the producer and the consumer may be different processes.
+// The first page of the region holds the head and the tail of the queue as
+// atomic uint64s, followed by the data pages which are mapped twice back to
+// back, hence, readable and writable bytes are always contiguous in memory
+// and reads and writes never need to wrap around the end of the buffer.
+// Write, Writable and Commit must only be called by the producer whereas
+// Read, Readable and Consume must only be called by the consumer. After
+// Close, Len returns 0, Writable and Readable return nil, Write and Read
+// return ErrUnmappedMemory and Commit and Consume panic with it.
+type RingBuffer struct {
+	// m maps the header and the data pages, the second copy of the data
+	// pages, mirror, is mapped right after it in the same reserved region.
+	m        *File
+	data     []byte
+	mirror   []byte
+	capacity uint64
+}
+
+// NewRingBuffer maps a ring buffer of given capacity stored in file f. The
+// file is extended to one page plus capacity bytes if it is smaller, data
+// in an existing ring buffer is preserved. capacity must be a multiple of
+// page size and must match the capacity of an existing ring buffer in f.
+func NewRingBuffer(f *os.File, capacity int) (*RingBuffer, error) {
+	if err := checkCapacity(capacity); err != nil {
+		return nil, err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if size := int64(pageSize + capacity); info.Size() < size {
+		if err := f.Truncate(size); err != nil {
+			return nil, err
+		}
+	}
+
+	return newRingBuffer(f, capacity)
+}
+
+// OpenRingBuffer opens the named file, creating it if it does not exist,
+// and maps the ring buffer stored in it as documented for NewRingBuffer.
+// The returned RingBuffer owns the opened file and must be closed using Close.
+func OpenRingBuffer(path string, capacity int) (*RingBuffer, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := NewRingBuffer(f, capacity)
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+
+	r.m.ownsFile = true
+	return r, nil
+}
+
+// NewAnonymousRingBuffer creates a ring buffer of given capacity that is not
+// backed by any file, the ring buffer is shared with the child processes
+// created afterwards. capacity must be a multiple of page size.
+// NewAnonymousRingBuffer is only supported on linux.
+func NewAnonymousRingBuffer(capacity int) (*RingBuffer, error) {
+	if err := checkCapacity(capacity); err != nil {
+		return nil, err
+	}
+
+	return newRingBuffer(nil, capacity)
+}
+
+func checkCapacity(capacity int) error {
+	if capacity <= 0 || capacity%pageSize != 0 {
+		return fmt.Errorf("%w: capacity %d is not a multiple of page size %d",
+			ErrInvalidCapacity, capacity, pageSize)
+	}
+
+	return nil
+}
+
+// newRingBuffer reserves the address space for the header page and two copies
+// of the data pages, maps the header and the data pages of f at the start of
+// the reserved region and the data pages once more right after them.
+func newRingBuffer(f *os.File, capacity int) (*RingBuffer, error) {
+	reserved, err := mmap(0, pageSize+2*capacity, syscall.PROT_NONE,
+		syscall.MAP_ANON|syscall.MAP_PRIVATE, -1, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	flags := syscall.MAP_SHARED
+	if f == nil {
+		flags |= syscall.MAP_ANON
+	}
+	o := &options{addr: addrOf(reserved), mapFlags: syscall.MAP_FIXED}
+	m, err := newMmap(f, 0, pageSize+capacity, syscall.PROT_READ|syscall.PROT_WRITE, flags, o)
+	if err != nil {
+		_ = munmap(reserved)
+		return nil, err
+	}
+
+	r := &RingBuffer{
+		m:        m,
+		data:     reserved[pageSize:],
+		mirror:   reserved[pageSize+capacity:],
+		capacity: uint64(capacity),
+	}
+	if err := r.mapMirror(f); err != nil {
+		_ = munmap(reserved)
+		return nil, err
+	}
+	if err := r.checkHeader(); err != nil {
+		_ = munmap(reserved)
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// mapMirror maps the data pages of f, or of the shared anonymous
+// memory if f is nil, once more over the mirror region.
+func (r *RingBuffer) mapMirror(f *os.File) error {
+	if f == nil {
+		return mirror(r.m.mapping[pageSize:], addrOf(r.mirror))
+	}
+
+	_, err := mmap(addrOf(r.mirror), len(r.mirror), syscall.PROT_READ|syscall.PROT_WRITE,
+		syscall.MAP_SHARED|syscall.MAP_FIXED, int(f.Fd()), int64(pageSize))
+	return err
+}
+
+// checkHeader stores the capacity in a new ring buffer and validates
+// the header of an existing ring buffer.
+func (r *RingBuffer) checkHeader() error {
+	if r.m.CompareAndSwapUint64At(0, r.capacity, ringCapacityOffset) {
+		return nil
+	}
+
+	if stored := r.m.AtomicLoadUint64At(ringCapacityOffset); stored != r.capacity {
+		return fmt.Errorf("%w: ring buffer capacity %d, expected %d",
+			ErrInvalidCapacity, stored, r.capacity)
+	}
+	if head, tail := r.head(), r.tail(); tail-head > r.capacity {
+		return fmt.Errorf("%w: ring buffer head %d and tail %d are inconsistent",
+			ErrIndexOutOfBound, head, tail)
+	}
+
+	return nil
+}
+
+// head returns the number of bytes read from the ring buffer so far.
+// head panics with ErrUnmappedMemory if the ring buffer is closed.
+func (r *RingBuffer) head() uint64 {
+	return r.m.AtomicLoadUint64At(ringHeadOffset)
+}
+
+// tail returns the number of bytes written to the ring buffer so far.
+// tail panics with ErrUnmappedMemory if the ring buffer is closed.
+func (r *RingBuffer) tail() uint64 {
+	return r.m.AtomicLoadUint64At(ringTailOffset)
+}
+
+// Cap returns the capacity of the ring buffer in bytes.
+func (r *RingBuffer) Cap() int {
+	return int(r.capacity)
+}
+
+// Len returns the number of bytes written but not yet read.
+func (r *RingBuffer) Len() int {
+	if r.data == nil {
+		return 0
+	}
+
+	head := r.head()
+	return int(r.tail() - head)
+}
+
+// Writable returns the slice referring to the free space of the ring buffer.
+// Bytes copied to the slice are made available to the consumer by Commit.
+func (r *RingBuffer) Writable() []byte {
+	if r.data == nil {
+		return nil
+	}
+
+	tail := r.tail()
+	free := r.capacity - (tail - r.head())
+	start := tail % r.capacity
+	return r.data[start : start+free : start+free]
+}
+
+// Commit makes n bytes written to the slice returned by Writable available
+// to the consumer. Commit panics with ErrIndexOutOfBound if n is negative
+// or more than the free space of the ring buffer.
+func (r *RingBuffer) Commit(n int) {
+	tail := r.tail()
+	if n < 0 || uint64(n) > r.capacity-(tail-r.head()) {
+		panic(ErrIndexOutOfBound)
+	}
+
+	r.m.AtomicStoreUint64At(tail+uint64(n), ringTailOffset)
+}
+
+// Readable returns the slice referring to the bytes written but not yet
+// read. The bytes must be released to the producer by calling Consume.
+func (r *RingBuffer) Readable() []byte {
+	if r.data == nil {
+		return nil
+	}
+
+	head := r.head()
+	used := r.tail() - head
+	start := head % r.capacity
+	return r.data[start : start+used : start+used]
+}
+
+// Consume releases n bytes of the slice returned by Readable to the producer.
+// Consume panics with ErrIndexOutOfBound if n is negative or more than the
+// number of bytes written but not yet read.
+func (r *RingBuffer) Consume(n int) {
+	head := r.head()
+	if n < 0 || uint64(n) > r.tail()-head {
+		panic(ErrIndexOutOfBound)
+	}
+
+	r.m.AtomicStoreUint64At(head+uint64(n), ringHeadOffset)
+}
+
+// Write copies as many bytes of p as fit in the free space of the ring
+// buffer and returns io.ErrShortWrite if all of p could not be written.
+func (r *RingBuffer) Write(p []byte) (int, error) {
+	if r.data == nil {
+		return 0, ErrUnmappedMemory
+	}
+
+	n := copy(r.Writable(), p)
+	r.Commit(n)
+	if n < len(p) {
+		return n, io.ErrShortWrite
+	}
+
+	return n, nil
+}
+
+// Read copies up to len(p) bytes written but not yet read to p.
+// Read does not wait for the producer, it returns 0 and a nil
+// error if the ring buffer is empty.
+func (r *RingBuffer) Read(p []byte) (int, error) {
+	if r.data == nil {
+		return 0, ErrUnmappedMemory
+	}
+
+	n := copy(p, r.Readable())
+	r.Consume(n)
+	return n, nil
+}
+
+// Flush flushes the header and the data pages of
+// a ring buffer stored in a file to disk.
+func (r *RingBuffer) Flush(flags int) error {
+	return r.m.FlushRange(0, r.m.length, flags)
+}
+
+// Close flushes a ring buffer stored in a file to disk, unmaps the ring
+// buffer and closes the file if it was opened using OpenRingBuffer.
+func (r *RingBuffer) Close() error {
+	if r.data == nil {
+		return ErrUnmappedMemory
+	}
+
+	errFlush := r.Flush(syscall.MS_SYNC)
+	errClose := r.m.Close()
+	errUnmap := munmap(r.mirror)
+	r.data = nil
+	r.mirror = nil
+
+	return errors.Join(errFlush, errClose, errUnmap)
+}
@@ -0,0 +1,330 @@
+package mmap
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// SegmentedFile provides a single address space spanning multiple fixed size
+// segment files in a directory, named seg-000001.dat, seg-000002.dat and so
+// on. Segment n holds the bytes from offset (n-1)*segmentSize until offset
+// n*segmentSize. New segments are created when data is written past the end
+// of the last segment and old segments can be deleted using DeleteBefore,
+// offsets of the remaining segments do not change.
+type SegmentedFile struct {
+	mu         sync.RWMutex
+	concurrent bool
+	closed     bool
+
+	dir         string
+	segmentSize int64
+	first       int64
+	segments    []*File
+	opts        []Option
+}
+
+// OpenSegmented opens the segment files in dir, creating dir if it does not
+// exist. Options are used to map each segment, the options WithOffset,
+// WithLength and WithGrowthPolicy are ignored. Segments in dir must be
+// contiguous and must have been created with the same segment size.
+// The returned SegmentedFile must be closed using Close.
+func OpenSegmented(dir string, segmentSize int64, opts ...Option) (*SegmentedFile, error) {
+	if segmentSize <= 0 {
+		return nil, fmt.Errorf("%w: invalid segment size %d", ErrIndexOutOfBound, segmentSize)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	numbers, err := segmentNumbers(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &SegmentedFile{
+		concurrent:  newOptions(opts).concurrent,
+		dir:         dir,
+		segmentSize: segmentSize,
+		opts: append(opts[:len(opts):len(opts)],
+			WithOffset(0), WithLength(int(segmentSize)), WithGrowthPolicy(nil)),
+	}
+	if len(numbers) > 0 {
+		s.first = numbers[0] - 1
+	}
+
+	for i, n := range numbers {
+		if n != numbers[0]+int64(i) {
+			_ = s.Close()
+			return nil, fmt.Errorf("segment %d missing in %s", numbers[0]+int64(i), dir)
+		}
+		if err := s.addSegment(); err != nil {
+			_ = s.Close()
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+// segmentNumbers returns the sorted numbers of the segment files in dir.
+func segmentNumbers(dir string) ([]int64, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var numbers []int64
+	for _, entry := range entries {
+		name, prefixed := strings.CutPrefix(entry.Name(), "seg-")
+		name, suffixed := strings.CutSuffix(name, ".dat")
+		if !prefixed || !suffixed {
+			continue
+		}
+		if n, err := strconv.ParseInt(name, 10, 64); err == nil && n > 0 {
+			numbers = append(numbers, n)
+		}
+	}
+
+	slices.Sort(numbers)
+	return numbers, nil
+}
+
+// segmentPath returns the path of the segment file at given index,
+// index of segment n is n-1.
+func (s *SegmentedFile) segmentPath(index int64) string {
+	return filepath.Join(s.dir, fmt.Sprintf("seg-%06d.dat", index+1))
+}
+
+// addSegment opens or creates the segment after the last segment.
+// Caller must hold the write lock.
+func (s *SegmentedFile) addSegment() error {
+	index := s.first + int64(len(s.segments))
+	m, err := Open(s.segmentPath(index), os.O_RDWR|os.O_CREATE, s.opts...)
+	if err != nil {
+		return err
+	}
+
+	s.segments = append(s.segments, m)
+	return nil
+}
+
+// Start returns the offset of the first byte that is not deleted.
+func (s *SegmentedFile) Start() int64 {
+	s.rlock()
+	defer s.runlock()
+
+	return s.first * s.segmentSize
+}
+
+// Size returns the offset of the end of the last segment.
+func (s *SegmentedFile) Size() int64 {
+	s.rlock()
+	defer s.runlock()
+
+	return s.size()
+}
+
+func (s *SegmentedFile) size() int64 {
+	return (s.first + int64(len(s.segments))) * s.segmentSize
+}
+
+// boundaryChecks panics if numBytes cannot be read or written starting
+// at given offset in the segments. Caller must hold the read lock.
+func (s *SegmentedFile) boundaryChecks(offset, numBytes int64) {
+	if s.closed {
+		panic(ErrUnmappedMemory)
+	} else if offset < s.first*s.segmentSize || numBytes < 0 || offset > s.size()-numBytes {
+		panic(ErrIndexOutOfBound)
+	}
+}
+
+// segment returns the segment containing offset along with
+// offset relative to the start of the segment.
+func (s *SegmentedFile) segment(offset int64) (*File, int64) {
+	return s.segments[offset/s.segmentSize-s.first], offset % s.segmentSize
+}
+
+// grow creates new segments, if required, so that numBytes can
+// be written starting at the given offset. grow must be called
+// without holding any lock on s.
+func (s *SegmentedFile) grow(offset, numBytes int64) error {
+	required := offset + numBytes
+	s.rlock()
+	fits := required <= s.size()
+	s.runlock()
+	if fits {
+		return nil
+	}
+
+	s.lock()
+	defer s.unlock()
+
+	for !s.closed && required > s.size() {
+		if err := s.addSegment(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ReadAt copies data to dest slice from the segments starting at given
+// offset and returns number of bytes copied to the dest slice, which is
+// the min value of len(dest) and (s.Size() - offset).
+// err is always nil, hence, can be ignored.
+func (s *SegmentedFile) ReadAt(dest []byte, offset int64) (int, error) {
+	s.rlock()
+	defer s.runlock()
+
+	s.boundaryChecks(offset, 1)
+	return s.copyAt(dest, offset, (*File).ReadAt), nil
+}
+
+// WriteAt copies all of src to the segments starting at given offset, new
+// segments are created if src does not fit in the existing segments. WriteAt
+// returns number of bytes copied and an error if a segment cannot be created.
+func (s *SegmentedFile) WriteAt(src []byte, offset int64) (int, error) {
+	if err := s.grow(offset, int64(len(src))); err != nil {
+		return 0, err
+	}
+
+	s.rlock()
+	defer s.runlock()
+
+	s.boundaryChecks(offset, 1)
+	return s.copyAt(src, offset, (*File).WriteAt), nil
+}
+
+// copyAt calls fn for the part of b in each segment starting at given offset
+// until b is copied or the last segment ends. Caller must hold the read lock.
+func (s *SegmentedFile) copyAt(b []byte, offset int64, fn func(*File, []byte, int64) (int, error)) int {
+	var n int
+	for n < len(b) && offset < s.size() {
+		m, segmentOffset := s.segment(offset)
+		k, _ := fn(m, b[n:min(len(b), n+int(s.segmentSize-segmentOffset))], segmentOffset)
+		n += k
+		offset += int64(k)
+	}
+
+	return n
+}
+
+// ReadUint64At reads uint64 from offset. The
+// uint64 may span the end of a segment.
+func (s *SegmentedFile) ReadUint64At(offset int64) uint64 {
+	s.rlock()
+	defer s.runlock()
+
+	s.boundaryChecks(offset, 8)
+	if m, segmentOffset := s.segment(offset); segmentOffset+8 <= s.segmentSize {
+		return m.ReadUint64At(segmentOffset)
+	}
+
+	var b [8]byte
+	s.copyAt(b[:], offset, (*File).ReadAt)
+	return binary.LittleEndian.Uint64(b[:])
+}
+
+// WriteUint64At writes num at offset, a new segment
+// is created if num does not fit in the existing segments.
+func (s *SegmentedFile) WriteUint64At(num uint64, offset int64) {
+	if err := s.grow(offset, 8); err != nil {
+		panic(err)
+	}
+
+	s.rlock()
+	defer s.runlock()
+
+	s.boundaryChecks(offset, 8)
+	if m, segmentOffset := s.segment(offset); segmentOffset+8 <= s.segmentSize {
+		m.WriteUint64At(num, segmentOffset)
+		return
+	}
+
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], num)
+	s.copyAt(b[:], offset, (*File).WriteAt)
+}
+
+// DeleteBefore deletes the segments that end at or before the given offset,
+// except the last segment, which is never deleted. Reading or writing the
+// deleted segments panics with ErrIndexOutOfBound.
+func (s *SegmentedFile) DeleteBefore(offset int64) error {
+	s.lock()
+	defer s.unlock()
+
+	for len(s.segments) > 1 && (s.first+1)*s.segmentSize <= offset {
+		if err := s.segments[0].Close(); err != nil {
+			return err
+		}
+		if err := os.Remove(s.segmentPath(s.first)); err != nil {
+			return err
+		}
+
+		s.segments[0] = nil
+		s.segments = s.segments[1:]
+		s.first++
+	}
+
+	return nil
+}
+
+// Flush flushes the modified pages of all the segments to disk.
+func (s *SegmentedFile) Flush(flags int) error {
+	s.rlock()
+	defer s.runlock()
+
+	var errs []error
+	for _, m := range s.segments {
+		errs = append(errs, m.Flush(flags))
+	}
+
+	return errors.Join(errs...)
+}
+
+// Close flushes and closes all the segments. SegmentedFile
+// must not be used after calling Close.
+func (s *SegmentedFile) Close() error {
+	s.lock()
+	defer s.unlock()
+
+	var errs []error
+	for _, m := range s.segments {
+		errs = append(errs, m.Close())
+	}
+
+	s.segments = nil
+	s.closed = true
+	return errors.Join(errs...)
+}
+
+func (s *SegmentedFile) rlock() {
+	if s.concurrent {
+		s.mu.RLock()
+	}
+}
+
+func (s *SegmentedFile) runlock() {
+	if s.concurrent {
+		s.mu.RUnlock()
+	}
+}
+
+func (s *SegmentedFile) lock() {
+	if s.concurrent {
+		s.mu.Lock()
+	}
+}
+
+func (s *SegmentedFile) unlock() {
+	if s.concurrent {
+		s.mu.Unlock()
+	}
+}
@@ -0,0 +1,37 @@
+package mmap
+
+// WithConcurrentAccess makes File safe for concurrent use by multiple goroutines.
+// Functions accessing the mapped region hold a read lock for their duration
+// whereas Unmap, Close, Resize and Grow hold a write lock, hence, Unmap waits
+// for the in-flight accessors to finish and the accessors called afterwards
+// fail with ErrUnmappedMemory. Without this option, File must not be accessed
+// concurrently if any goroutine unmaps or resizes the mapping.
+func WithConcurrentAccess() Option {
+	return func(o *options) {
+		o.concurrent = true
+	}
+}
+
+func (m *File) rlock() {
+	if m.concurrent {
+		m.mu.RLock()
+	}
+}
+
+func (m *File) runlock() {
+	if m.concurrent {
+		m.mu.RUnlock()
+	}
+}
+
+func (m *File) lock() {
+	if m.concurrent {
+		m.mu.Lock()
+	}
+}
+
+func (m *File) unlock() {
+	if m.concurrent {
+		m.mu.Unlock()
+	}
+}
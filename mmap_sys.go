@@ -0,0 +1,47 @@
+package mmap
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+// mmap is a thin wrapper around mmap syscall. Unlike syscall.Mmap, it allows
+// passing an address hint and the returned slice can be unmapped using munmap
+// irrespective of how the memory region is modified later on (e.g. by mremap).
+func mmap(addr uintptr, length int, prot, flags, fd int, offset int64) ([]byte, error) {
+	if length <= 0 {
+		return nil, syscall.EINVAL
+	}
+
+	r, _, err := syscall.Syscall6(syscall.SYS_MMAP, addr, uintptr(length),
+		uintptr(prot), uintptr(flags), uintptr(fd), uintptr(offset))
+	if err != 0 {
+		return nil, err
+	}
+
+	return bytesAt(r, length), nil
+}
+
+// munmap unmaps the memory region referred by b.
+func munmap(b []byte) error {
+	if len(b) == 0 {
+		return syscall.EINVAL
+	}
+
+	_, _, err := syscall.Syscall(syscall.SYS_MUNMAP, addrOf(b), uintptr(len(b)), 0)
+	if err != 0 {
+		return err
+	}
+
+	return nil
+}
+
+// bytesAt returns a slice of given length referring to the memory at addr.
+func bytesAt(addr uintptr, length int) []byte {
+	return unsafe.Slice((*byte)(*(*unsafe.Pointer)(unsafe.Pointer(&addr))), length)
+}
+
+// addrOf returns the address of the first byte of b.
+func addrOf(b []byte) uintptr {
+	return uintptr(unsafe.Pointer(&b[0]))
+}
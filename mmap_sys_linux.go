@@ -0,0 +1,73 @@
+package mmap
+
+import (
+	"os"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+const (
+	mremapMayMove = 0x1
+	mremapFixed   = 0x2
+
+	futexWait = 0
+	futexWake = 1
+)
+
+// remap resizes the memory mapping of m to newLength bytes
+// using mremap, the mapping is moved if required.
+func (m *File) remap(newLength int) ([]byte, error) {
+	r, _, err := syscall.Syscall6(syscall.SYS_MREMAP, addrOf(m.mapping),
+		uintptr(len(m.mapping)), uintptr(newLength), mremapMayMove, 0, 0)
+	if err != 0 {
+		return nil, err
+	}
+
+	return bytesAt(r, newLength), nil
+}
+
+// mirror maps the pages of the shared mapping b once more at addr using
+// mremap with zero old size, addr must refer to a reserved memory region.
+func mirror(b []byte, addr uintptr) error {
+	_, _, err := syscall.Syscall6(syscall.SYS_MREMAP, addrOf(b), 0,
+		uintptr(len(b)), mremapMayMove|mremapFixed, addr, 0)
+	if err != 0 {
+		return err
+	}
+
+	return nil
+}
+
+// wait blocks on the uint32 at offset using futex.
+func (m *File) wait(val uint32, offset int64, timeout time.Duration) error {
+	addr := m.futexAddr(offset)
+
+	var ts *syscall.Timespec
+	if timeout >= 0 {
+		t := syscall.NsecToTimespec(timeout.Nanoseconds())
+		ts = &t
+	}
+
+	_, _, err := syscall.Syscall6(syscall.SYS_FUTEX, addr, futexWait,
+		uintptr(val), uintptr(unsafe.Pointer(ts)), 0, 0)
+	switch err {
+	case 0, syscall.EAGAIN, syscall.EINTR:
+		return nil
+	case syscall.ETIMEDOUT:
+		return os.ErrDeadlineExceeded
+	default:
+		return err
+	}
+}
+
+// wake wakes up the waiters blocked on the uint32 at offset using futex.
+func (m *File) wake(offset int64, n int) (int, error) {
+	addr := m.futexAddr(offset)
+	r, _, err := syscall.Syscall(syscall.SYS_FUTEX, addr, futexWake, uintptr(n))
+	if err != 0 {
+		return 0, err
+	}
+
+	return int(r), nil
+}
@@ -0,0 +1,60 @@
+//go:build !linux
+
+package mmap
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// pollInterval is the interval at which wait polls the value.
+const pollInterval = 100 * time.Microsecond
+
+// remap resizes the memory mapping of m to newLength bytes by creating a new
+// mapping and unmapping the older one. Data in anonymous mappings is copied.
+func (m *File) remap(newLength int) ([]byte, error) {
+	fd := -1
+	if m.flags&syscall.MAP_ANON == 0 {
+		fd = int(m.file.Fd())
+	}
+
+	mapping, err := mmap(0, newLength, m.prot, m.flags, fd, m.offset)
+	if err != nil {
+		return nil, err
+	}
+
+	if fd == -1 {
+		copy(mapping, m.mapping)
+	}
+	if err := munmap(m.mapping); err != nil {
+		_ = munmap(mapping)
+		return nil, err
+	}
+
+	return mapping, nil
+}
+
+// mirror is not supported for anonymous mappings on this platform.
+func mirror([]byte, uintptr) error {
+	return syscall.ENOTSUP
+}
+
+// wait polls the uint32 at offset until it changes or timeout elapses.
+func (m *File) wait(val uint32, offset int64, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for m.AtomicLoadUint32At(offset) == val {
+		if timeout >= 0 && time.Now().After(deadline) {
+			return os.ErrDeadlineExceeded
+		}
+		time.Sleep(pollInterval)
+	}
+
+	return nil
+}
+
+// wake does nothing because wait polls the value.
+func (m *File) wake(offset int64, _ int) (int, error) {
+	m.futexAddr(offset)
+	return 0, nil
+}
@@ -3,12 +3,19 @@ package mmap
 import (
 	"archive/zip"
 	"bytes"
+	"errors"
 	"io"
+	"math"
 	"os"
+	"os/exec"
 	"path"
+	"runtime"
 	"strings"
+	"sync"
 	"syscall"
 	"testing"
+	"time"
+	"unsafe"
 )
 
 var (
@@ -637,13 +644,13 @@ func TestDirtyFlag(t *testing.T) {
 	}()
 
 	m.WriteUint64At(0, 0)
-	if !m.dirty {
+	if m.dirty.empty() {
 		t.Fatalf("expected file to be dirty")
 	}
 	if err := m.Flush(syscall.MS_SYNC); err != nil {
 		t.Fatalf("error in calling flush :: %v", err)
 	}
-	if m.dirty {
+	if !m.dirty.empty() {
 		t.Fatalf("expected file to be not dirty")
 	}
 	_ = m.ReadUint64At(0)
@@ -651,40 +658,1953 @@ func TestDirtyFlag(t *testing.T) {
 	if err := m.Flush(syscall.MS_SYNC); err != nil {
 		t.Fatalf("error in calling flush :: %v", err)
 	}
-	if m.dirty {
+	if !m.dirty.empty() {
 		t.Fatalf("expected file to be not dirty")
 	}
 
 	_ = m.WriteStringAt("string", 0)
-	if !m.dirty {
+	if m.dirty.empty() {
 		t.Fatalf("expected file to be dirty")
 	}
 	if err := m.Flush(syscall.MS_SYNC); err != nil {
 		t.Fatalf("error in calling flush :: %v", err)
 	}
-	if m.dirty {
+	if !m.dirty.empty() {
 		t.Fatalf("expected file to be not dirty")
 	}
 	sb := &strings.Builder{}
 	sb.Grow(len("string"))
 	_ = m.ReadStringAt(sb, 0, 6)
-	if m.dirty {
+	if !m.dirty.empty() {
 		t.Fatalf("expected file to be not dirty")
 	}
 
 	_, _ = m.WriteAt([]byte{1, 2}, 0)
-	if !m.dirty {
+	if m.dirty.empty() {
 		t.Fatalf("expected file to be dirty")
 	}
 	if err := m.Flush(syscall.MS_SYNC); err != nil {
 		t.Fatalf("error in calling flush :: %v", err)
 	}
-	if m.dirty {
+	if !m.dirty.empty() {
 		t.Fatalf("expected file to be not dirty")
 	}
 	bs := make([]byte, 2)
 	_, _ = m.ReadAt(bs, 0)
-	if m.dirty {
+	if !m.dirty.empty() {
 		t.Fatalf("expected file to be not dirty")
 	}
 }
+
+func TestAnonymousMmap(t *testing.T) {
+	t.Parallel()
+
+	for _, newFn := range []func(int, int, ...Option) (*File, error){NewAnonymousMmap, NewSharedAnonymousMmap} {
+		m, err := newFn(len(testData), protPage)
+		if err != nil {
+			t.Fatalf("error in mapping :: %v", err)
+		}
+
+		if m.ReadUint64At(0) != 0 {
+			t.Fatalf("expected anonymous mapping to be zero initialized")
+		}
+		if _, err := m.WriteAt(testData, 0); err != nil {
+			t.Fatalf("error in writing to mapped region :: %v", err)
+		}
+		data := make([]byte, len(testData))
+		if _, err := m.ReadAt(data, 0); err != nil {
+			t.Fatalf("error in reading :: %v", err)
+		}
+		if !bytes.Equal(testData, data) {
+			t.Fatalf("mapped data is not equal testData: %v, %v", data, testData)
+		}
+
+		// No backing file, flush is a no-op
+		if err := m.Flush(syscall.MS_SYNC); err != nil {
+			t.Fatalf("error in calling flush :: %v", err)
+		}
+		if !m.dirty.empty() {
+			t.Fatalf("expected file to be not dirty")
+		}
+
+		if err := m.Unmap(); err != nil {
+			t.Fatalf("error in calling unmap :: %v", err)
+		}
+	}
+}
+
+func TestPrivateFileMmap(t *testing.T) {
+	t.Parallel()
+
+	testPath := path.Join(t.TempDir(), "m.txt")
+	setup(t, testPath)
+
+	f, err := os.OpenFile(testPath, os.O_RDONLY, 0644)
+	if err != nil {
+		t.Fatalf("error in opening file :: %v", err)
+	}
+	defer func() {
+		if err := f.Close(); err != nil {
+			t.Fatalf("error in closing file :: %v", err)
+		}
+	}()
+
+	m, err := NewPrivateFileMmap(f, 0, len(testData), protPage)
+	if err != nil {
+		t.Fatalf("error in mapping :: %v", err)
+	}
+	defer func() {
+		if err := m.Unmap(); err != nil {
+			t.Fatalf("error in calling unmap :: %v", err)
+		}
+	}()
+
+	_ = m.WriteStringAt("abc", 0)
+	if err := m.Flush(syscall.MS_SYNC); err != nil {
+		t.Fatalf("error in calling flush :: %v", err)
+	}
+
+	sb := &strings.Builder{}
+	sb.Grow(3)
+	_ = m.ReadStringAt(sb, 0, 3)
+	if sb.String() != "abc" {
+		t.Fatalf("expected mapped region to be modified, found: %v", sb.String())
+	}
+
+	fileData, err := os.ReadFile(testPath)
+	if err != nil {
+		t.Fatalf("error in reading file :: %v", err)
+	}
+	if !bytes.Equal(fileData, testData) {
+		t.Fatalf("unexpected modification in file :: %v", string(fileData))
+	}
+}
+
+func TestOpenCreate(t *testing.T) {
+	t.Parallel()
+
+	testPath := path.Join(t.TempDir(), "m.txt")
+	pageSize := os.Getpagesize()
+
+	m, err := Create(testPath, int64(pageSize+len(testData)))
+	if err != nil {
+		t.Fatalf("error in creating mapped file :: %v", err)
+	}
+	if _, err := m.WriteAt(testData, int64(pageSize)); err != nil {
+		t.Fatalf("error in writing to mapped region :: %v", err)
+	}
+	if err := m.Close(); err != nil {
+		t.Fatalf("error in closing mapped file :: %v", err)
+	}
+
+	// Read from an offset that is not page aligned
+	offset := int64(pageSize + 10)
+	m, err = Open(testPath, os.O_RDONLY, WithOffset(offset), WithLength(5))
+	if err != nil {
+		t.Fatalf("error in opening mapped file :: %v", err)
+	}
+	data := make([]byte, 10)
+	if n, err := m.ReadAt(data, 0); err != nil {
+		t.Fatalf("error in reading :: %v", err)
+	} else if n != 5 {
+		t.Fatalf("error in reading, exp: 5, actual: %v", n)
+	}
+	if !bytes.Equal(data[:5], testData[10:15]) {
+		t.Fatalf("mapped data is not equal testData: %v, %v", data[:5], testData[10:15])
+	}
+	if err := m.Close(); err != nil {
+		t.Fatalf("error in closing mapped file :: %v", err)
+	}
+
+	// Read only file cannot be extended
+	if _, err := Open(testPath, os.O_RDONLY, WithLength(2*pageSize)); !errors.Is(err, ErrIndexOutOfBound) {
+		t.Fatalf("different error than expected in Open :: %v", err)
+	}
+
+	// File opened for writing is extended to fit the mapped region
+	m, err = Open(testPath, os.O_RDWR, WithOffset(offset), WithLength(2*pageSize))
+	if err != nil {
+		t.Fatalf("error in opening mapped file :: %v", err)
+	}
+	m.WriteUint64At(10000000000, int64(2*pageSize-8))
+	if err := m.Close(); err != nil {
+		t.Fatalf("error in closing mapped file :: %v", err)
+	}
+	info, err := os.Stat(testPath)
+	if err != nil {
+		t.Fatalf("error in calling stat :: %v", err)
+	}
+	if info.Size() != offset+int64(2*pageSize) {
+		t.Fatalf("unexpected file size, exp: %v, actual: %v", offset+int64(2*pageSize), info.Size())
+	}
+
+	// Close after Unmap only closes the file
+	m, err = Open(testPath, os.O_RDWR)
+	if err != nil {
+		t.Fatalf("error in opening mapped file :: %v", err)
+	}
+	if m.ReadUint64At(offset+int64(2*pageSize-8)) != 10000000000 {
+		t.Fatalf("data written before Close is not persisted")
+	}
+	if err := m.Unmap(); err != nil {
+		t.Fatalf("error in calling unmap :: %v", err)
+	}
+	if err := m.Close(); err != nil {
+		t.Fatalf("error in closing mapped file :: %v", err)
+	}
+}
+
+func TestResize(t *testing.T) {
+	t.Parallel()
+
+	testPath := path.Join(t.TempDir(), "m.txt")
+	setup(t, testPath)
+
+	m, err := Open(testPath, os.O_RDWR, WithOffset(10))
+	if err != nil {
+		t.Fatalf("error in opening mapped file :: %v", err)
+	}
+	defer func() {
+		if err := m.Close(); err != nil {
+			t.Fatalf("error in closing mapped file :: %v", err)
+		}
+	}()
+
+	newLength := int64(3 * os.Getpagesize())
+	if err := m.Resize(newLength); err != nil {
+		t.Fatalf("error in resizing :: %v", err)
+	}
+	m.WriteUint64At(10000000000, newLength-8)
+	sb := &strings.Builder{}
+	sb.Grow(len(testData) - 10)
+	_ = m.ReadStringAt(sb, 0, int64(len(testData)-10))
+	if sb.String() != string(testData[10:]) {
+		t.Fatalf("mapped data changed after resize: %v", sb.String())
+	}
+	info, err := os.Stat(testPath)
+	if err != nil {
+		t.Fatalf("error in calling stat :: %v", err)
+	}
+	if info.Size() != 10+newLength {
+		t.Fatalf("unexpected file size, exp: %v, actual: %v", 10+newLength, info.Size())
+	}
+
+	// Shrinking the mapping does not shrink the file
+	if err := m.Resize(8); err != nil {
+		t.Fatalf("error in resizing :: %v", err)
+	}
+	if err := m.Grow(8); err != nil {
+		t.Fatalf("error in growing :: %v", err)
+	}
+	if m.length != 16 {
+		t.Fatalf("unexpected length, exp: 16, actual: %v", m.length)
+	}
+	func() {
+		defer func() {
+			if err := recover(); err != ErrIndexOutOfBound {
+				t.Fatalf("different error than expected in ReadUint64At :: %v", err)
+			}
+		}()
+
+		_ = m.ReadUint64At(16)
+	}()
+	if err := m.Resize(0); !errors.Is(err, ErrIndexOutOfBound) {
+		t.Fatalf("different error than expected in Resize :: %v", err)
+	}
+
+	shared, err := NewSharedAnonymousMmap(8, protPage)
+	if err != nil {
+		t.Fatalf("error in mapping :: %v", err)
+	}
+	if err := shared.Grow(8); err != ErrNotResizable {
+		t.Fatalf("different error than expected in Grow :: %v", err)
+	}
+	if err := shared.Unmap(); err != nil {
+		t.Fatalf("error in calling unmap :: %v", err)
+	}
+}
+
+func TestGrowthPolicy(t *testing.T) {
+	t.Parallel()
+
+	if n := GrowDoubling()(100, 300); n != 400 {
+		t.Fatalf("unexpected length from GrowDoubling, exp: 400, actual: %v", n)
+	}
+	if n := GrowByChunk(64)(100, 300); n != 356 {
+		t.Fatalf("unexpected length from GrowByChunk, exp: 356, actual: %v", n)
+	}
+
+	m, err := NewAnonymousMmap(8, protPage, WithGrowthPolicy(GrowDoubling()))
+	if err != nil {
+		t.Fatalf("error in mapping :: %v", err)
+	}
+	defer func() {
+		if err := m.Unmap(); err != nil {
+			t.Fatalf("error in calling unmap :: %v", err)
+		}
+	}()
+
+	m.WriteUint64At(10000000000, 0)
+	if n, err := m.WriteAt(testData, 4); err != nil {
+		t.Fatalf("error in writing to mapped region :: %v", err)
+	} else if n != len(testData) {
+		t.Fatalf("error in writing, exp: %v, actual: %v", len(testData), n)
+	}
+	if m.length != 64 {
+		t.Fatalf("unexpected length, exp: 64, actual: %v", m.length)
+	}
+	if num := m.ReadUint64At(0) & 0xffffffff; num != 10000000000&0xffffffff {
+		t.Fatalf("data lost while growing, found: %v", num)
+	}
+
+	m.WriteUint64At(1, 100)
+	if m.length != 128 {
+		t.Fatalf("unexpected length, exp: 128, actual: %v", m.length)
+	}
+}
+
+func TestTryFunctions(t *testing.T) {
+	t.Parallel()
+
+	m, err := NewAnonymousMmap(len(testData), protPage)
+	if err != nil {
+		t.Fatalf("error in mapping :: %v", err)
+	}
+
+	if n, err := m.TryWriteAt(testData, 0); err != nil {
+		t.Fatalf("error in writing to mapped region :: %v", err)
+	} else if n != len(testData) {
+		t.Fatalf("error in writing, exp: %v, actual: %v", len(testData), n)
+	}
+	if _, err := m.TryWriteStringAt("abc", 34); err != nil {
+		t.Fatalf("error in writing to mapped region :: %v", err)
+	}
+	if err := m.TryWriteUint64At(10000000000, 8); err != nil {
+		t.Fatalf("error in writing to mapped region :: %v", err)
+	}
+	if num, err := m.TryReadUint64At(8); err != nil {
+		t.Fatalf("error in reading :: %v", err)
+	} else if num != 10000000000 {
+		t.Fatalf("error in TryReadUint64At, expected: %d, actual: %d", uint64(10000000000), num)
+	}
+	sb := &strings.Builder{}
+	sb.Grow(10)
+	if n, err := m.TryReadStringAt(sb, 30, 10); err != nil {
+		t.Fatalf("error in reading :: %v", err)
+	} else if n != 6 || sb.String() != "UVWXab" {
+		t.Fatalf("unexpected data read: %v", sb.String())
+	}
+	data := make([]byte, 2)
+	if _, err := m.TryReadAt(data, 0); err != nil {
+		t.Fatalf("error in reading :: %v", err)
+	} else if !bytes.Equal(data, testData[:2]) {
+		t.Fatalf("mapped data is not equal testData: %v, %v", data, testData[:2])
+	}
+
+	checkErr := func(err, expected error) {
+		t.Helper()
+		if !errors.Is(err, expected) {
+			t.Fatalf("different error than expected :: %v", err)
+		}
+	}
+
+	_, err = m.TryReadAt(data, 100)
+	checkErr(err, ErrIndexOutOfBound)
+	if !strings.Contains(err.Error(), "offset 100") {
+		t.Fatalf("error does not contain offending offset :: %v", err)
+	}
+	_, err = m.TryWriteAt(data, -1)
+	checkErr(err, ErrIndexOutOfBound)
+	_, err = m.TryReadStringAt(sb, 100, 1)
+	checkErr(err, ErrIndexOutOfBound)
+	_, err = m.TryWriteStringAt("a", 100)
+	checkErr(err, ErrIndexOutOfBound)
+	_, err = m.TryReadUint64At(int64(len(testData) - 7))
+	checkErr(err, ErrIndexOutOfBound)
+	checkErr(m.TryWriteUint64At(0, int64(len(testData)-4)), ErrIndexOutOfBound)
+
+	// Offsets that overflow when added to the length
+	_, err = m.TryReadUint64At(math.MaxInt64 - 2)
+	checkErr(err, ErrIndexOutOfBound)
+	_, err = m.TryReadAt(data, math.MaxInt64)
+	checkErr(err, ErrIndexOutOfBound)
+	checkErr(m.TryWriteUint64At(0, math.MaxInt64-2), ErrIndexOutOfBound)
+	func() {
+		defer func() {
+			if err := recover(); err != ErrIndexOutOfBound {
+				t.Fatalf("different error than expected :: %v", err)
+			}
+		}()
+
+		m.ReadUint64At(math.MaxInt64 - 2)
+	}()
+
+	if err := m.Unmap(); err != nil {
+		t.Fatalf("error in calling unmap :: %v", err)
+	}
+
+	_, err = m.TryReadAt(data, 0)
+	checkErr(err, ErrUnmappedMemory)
+	_, err = m.TryWriteAt(data, 0)
+	checkErr(err, ErrUnmappedMemory)
+	_, err = m.TryReadStringAt(sb, 0, 1)
+	checkErr(err, ErrUnmappedMemory)
+	_, err = m.TryWriteStringAt("a", 0)
+	checkErr(err, ErrUnmappedMemory)
+	_, err = m.TryReadUint64At(0)
+	checkErr(err, ErrUnmappedMemory)
+	checkErr(m.TryWriteUint64At(0, 0), ErrUnmappedMemory)
+}
+
+func TestIntegerAccessors(t *testing.T) {
+	t.Parallel()
+
+	m, err := NewAnonymousMmap(len(testData), protPage)
+	if err != nil {
+		t.Fatalf("error in mapping :: %v", err)
+	}
+	defer func() {
+		if err := m.Unmap(); err != nil {
+			t.Fatalf("error in calling unmap :: %v", err)
+		}
+	}()
+
+	m.WriteUint8At(0xab, 0)
+	m.WriteInt8At(-2, 1)
+	m.WriteUint16At(0x0102, 2)
+	m.WriteInt16At(-3, 4)
+	m.WriteUint32At(0x01020304, 6)
+	m.WriteInt32At(-4, 10)
+	m.WriteInt64At(-5, 14)
+	if m.ReadUint8At(0) != 0xab || m.ReadInt8At(1) != -2 || m.ReadUint16At(2) != 0x0102 ||
+		m.ReadInt16At(4) != -3 || m.ReadUint32At(6) != 0x01020304 || m.ReadInt32At(10) != -4 ||
+		m.ReadInt64At(14) != -5 {
+		t.Fatalf("integers read are not equal to integers written")
+	}
+
+	expected := []byte{0xab, 0xfe, 0x02, 0x01, 0xfd, 0xff, 0x04, 0x03, 0x02, 0x01}
+	actual := make([]byte, len(expected))
+	if _, err := m.ReadAt(actual, 0); err != nil {
+		t.Fatalf("error in reading :: %v", err)
+	}
+	if !bytes.Equal(expected, actual) {
+		t.Fatalf("unexpected little endian encoding, expected: %v, actual: %v", expected, actual)
+	}
+
+	be := m.BigEndian()
+	be.WriteUint16At(0x0102, 0)
+	be.WriteUint32At(0x01020304, 2)
+	be.WriteUint64At(0x0102030405060708, 6)
+	expected = []byte{0x01, 0x02, 0x01, 0x02, 0x03, 0x04, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08}
+	actual = make([]byte, len(expected))
+	if _, err := m.ReadAt(actual, 0); err != nil {
+		t.Fatalf("error in reading :: %v", err)
+	}
+	if !bytes.Equal(expected, actual) {
+		t.Fatalf("unexpected big endian encoding, expected: %v, actual: %v", expected, actual)
+	}
+	if be.ReadUint16At(0) != 0x0102 || be.ReadUint32At(2) != 0x01020304 ||
+		be.ReadUint64At(6) != 0x0102030405060708 || m.LittleEndian().ReadUint16At(0) != 0x0201 {
+		t.Fatalf("integers read are not equal to integers written")
+	}
+
+	be.WriteInt16At(-1, 0)
+	be.WriteInt32At(-2, 2)
+	be.WriteInt64At(-3, 6)
+	if be.ReadInt16At(0) != -1 || be.ReadInt32At(2) != -2 || be.ReadInt64At(6) != -3 {
+		t.Fatalf("integers read are not equal to integers written")
+	}
+
+	func() {
+		defer func() {
+			if err := recover(); err != ErrIndexOutOfBound {
+				t.Fatalf("different error than expected in ReadUint32At :: %v", err)
+			}
+		}()
+
+		_ = be.ReadUint32At(int64(len(testData) - 3))
+	}()
+}
+
+func TestFloatAccessors(t *testing.T) {
+	t.Parallel()
+
+	m, err := NewAnonymousMmap(len(testData), protPage)
+	if err != nil {
+		t.Fatalf("error in mapping :: %v", err)
+	}
+	defer func() {
+		if err := m.Unmap(); err != nil {
+			t.Fatalf("error in calling unmap :: %v", err)
+		}
+	}()
+
+	m.WriteFloat32At(1.5, 0)
+	m.WriteFloat64At(-2.25, 4)
+	m.WriteComplex64At(complex(1, -1), 12)
+	m.WriteComplex128At(complex(3.5, 4.5), 20)
+	if m.ReadFloat32At(0) != 1.5 || m.ReadFloat64At(4) != -2.25 ||
+		m.ReadComplex64At(12) != complex(1, -1) || m.ReadComplex128At(20) != complex(3.5, 4.5) {
+		t.Fatalf("floats read are not equal to floats written")
+	}
+	if m.ReadUint32At(0) != 0x3fc00000 {
+		t.Fatalf("unexpected float32 encoding: %x", m.ReadUint32At(0))
+	}
+
+	vec32 := []float32{1, 2.5, -3, 4}
+	m.WriteFloat32sAt(vec32, 2)
+	out32 := make([]float32, len(vec32))
+	m.ReadFloat32sAt(out32, 2)
+	for i := range vec32 {
+		if vec32[i] != out32[i] || m.ReadFloat32At(int64(2+4*i)) != vec32[i] {
+			t.Fatalf("float32 vector read is not equal to vector written: %v, %v", out32, vec32)
+		}
+	}
+
+	vec64 := []float64{1, -2.5, 3}
+	m.WriteFloat64sAt(vec64, 8)
+	out64 := make([]float64, len(vec64))
+	m.ReadFloat64sAt(out64, 8)
+	for i := range vec64 {
+		if vec64[i] != out64[i] {
+			t.Fatalf("float64 vector read is not equal to vector written: %v, %v", out64, vec64)
+		}
+	}
+
+	func() {
+		defer func() {
+			if err := recover(); err != ErrIndexOutOfBound {
+				t.Fatalf("different error than expected in ReadFloat64sAt :: %v", err)
+			}
+		}()
+
+		m.ReadFloat64sAt(make([]float64, 5), 0)
+	}()
+	func() {
+		defer func() {
+			if err := recover(); err != ErrIndexOutOfBound {
+				t.Fatalf("different error than expected in WriteFloat32sAt :: %v", err)
+			}
+		}()
+
+		m.WriteFloat32sAt(vec32, int64(len(testData)-8))
+	}()
+}
+
+func TestAtomicOperations(t *testing.T) {
+	t.Parallel()
+
+	testPath := path.Join(t.TempDir(), "m.txt")
+	setup(t, testPath)
+
+	m, err := Open(testPath, os.O_RDWR)
+	if err != nil {
+		t.Fatalf("error in opening mapped file :: %v", err)
+	}
+	defer func() {
+		if err := m.Close(); err != nil {
+			t.Fatalf("error in closing mapped file :: %v", err)
+		}
+	}()
+
+	// Another mapping of the same file shares the counters
+	other, err := Open(testPath, os.O_RDWR)
+	if err != nil {
+		t.Fatalf("error in opening mapped file :: %v", err)
+	}
+	defer func() {
+		if err := other.Close(); err != nil {
+			t.Fatalf("error in closing mapped file :: %v", err)
+		}
+	}()
+
+	m.AtomicStoreUint64At(10, 8)
+	if m.dirty.empty() {
+		t.Fatalf("expected file to be dirty")
+	}
+	if n := other.AtomicAddUint64At(5, 8); n != 15 {
+		t.Fatalf("unexpected value after AtomicAddUint64At, exp: 15, actual: %v", n)
+	}
+	if !m.CompareAndSwapUint64At(15, 20, 8) || m.CompareAndSwapUint64At(15, 30, 8) {
+		t.Fatalf("unexpected result from CompareAndSwapUint64At")
+	}
+	if n := other.AtomicLoadUint64At(8); n != 20 {
+		t.Fatalf("unexpected value after AtomicLoadUint64At, exp: 20, actual: %v", n)
+	}
+
+	m.AtomicStoreUint32At(1, 4)
+	if n := other.AtomicAddUint32At(2, 4); n != 3 {
+		t.Fatalf("unexpected value after AtomicAddUint32At, exp: 3, actual: %v", n)
+	}
+	if !other.CompareAndSwapUint32At(3, 4, 4) || other.CompareAndSwapUint32At(3, 5, 4) {
+		t.Fatalf("unexpected result from CompareAndSwapUint32At")
+	}
+	if n := m.AtomicLoadUint32At(4); n != 4 {
+		t.Fatalf("unexpected value after AtomicLoadUint32At, exp: 4, actual: %v", n)
+	}
+
+	func() {
+		defer func() {
+			if err := recover(); err != ErrUnalignedOffset {
+				t.Fatalf("different error than expected in AtomicLoadUint64At :: %v", err)
+			}
+		}()
+
+		_ = m.AtomicLoadUint64At(4)
+	}()
+	func() {
+		defer func() {
+			if err := recover(); err != ErrIndexOutOfBound {
+				t.Fatalf("different error than expected in AtomicStoreUint32At :: %v", err)
+			}
+		}()
+
+		m.AtomicStoreUint32At(0, int64(len(testData)))
+	}()
+}
+
+func TestRangeFunctions(t *testing.T) {
+	t.Parallel()
+
+	testPath := path.Join(t.TempDir(), "m.txt")
+	pageSize := int64(os.Getpagesize())
+
+	m, err := Create(testPath, 4*pageSize, WithOffset(10))
+	if err != nil {
+		t.Fatalf("error in creating mapped file :: %v", err)
+	}
+	defer func() {
+		if err := m.Close(); err != nil {
+			t.Fatalf("error in closing mapped file :: %v", err)
+		}
+	}()
+
+	offset := pageSize + 100
+	_ = m.WriteStringAt("record", offset)
+	if err := m.FlushRange(offset, 6, syscall.MS_SYNC); err != nil {
+		t.Fatalf("error in calling flush range :: %v", err)
+	}
+	fileData, err := os.ReadFile(testPath)
+	if err != nil {
+		t.Fatalf("error in reading file :: %v", err)
+	}
+	if string(fileData[10+offset:10+offset+6]) != "record" {
+		t.Fatalf("no modification in file :: %v", string(fileData[10+offset:10+offset+6]))
+	}
+
+	if err := m.AdviseRange(offset, 2*pageSize, syscall.MADV_WILLNEED); err != nil {
+		t.Fatalf("error in calling advise range :: %v", err)
+	}
+	if err := m.LockRange(offset, pageSize); err != nil {
+		t.Fatalf("error in calling lock range :: %v", err)
+	}
+	if err := m.UnlockRange(offset, pageSize); err != nil {
+		t.Fatalf("error in calling unlock range :: %v", err)
+	}
+
+	if err := m.FlushRange(offset, 4*pageSize, syscall.MS_SYNC); !errors.Is(err, ErrIndexOutOfBound) {
+		t.Fatalf("different error than expected in FlushRange :: %v", err)
+	}
+	if err := m.AdviseRange(-1, 1, syscall.MADV_WILLNEED); !errors.Is(err, ErrIndexOutOfBound) {
+		t.Fatalf("different error than expected in AdviseRange :: %v", err)
+	}
+	if err := m.LockRange(4*pageSize, 1); !errors.Is(err, ErrIndexOutOfBound) {
+		t.Fatalf("different error than expected in LockRange :: %v", err)
+	}
+}
+
+func TestDirtyRanges(t *testing.T) {
+	t.Parallel()
+
+	testPath := path.Join(t.TempDir(), "m.txt")
+	ps := int64(os.Getpagesize())
+
+	m, err := Create(testPath, 8*ps+10, WithOffset(10))
+	if err != nil {
+		t.Fatalf("error in creating mapped file :: %v", err)
+	}
+	defer func() {
+		if err := m.Close(); err != nil {
+			t.Fatalf("error in closing mapped file :: %v", err)
+		}
+	}()
+
+	checkRanges := func(expected []Range) {
+		t.Helper()
+		actual := m.DirtyRanges()
+		if len(actual) != len(expected) {
+			t.Fatalf("unexpected dirty ranges, expected: %v, actual: %v", expected, actual)
+		}
+		for i := range expected {
+			if expected[i] != actual[i] {
+				t.Fatalf("unexpected dirty ranges, expected: %v, actual: %v", expected, actual)
+			}
+		}
+	}
+
+	checkRanges(nil)
+	m.WriteUint64At(1, 0)
+	m.WriteUint64At(2, ps-10)
+	m.WriteUint64At(3, 2*ps-10)
+	_, _ = m.WriteAt(bytes.Repeat([]byte{1}, int(ps)+1), 5*ps-10)
+	m.WriteUint8At(4, 8*ps-1)
+	checkRanges([]Range{{0, 3*ps - 10}, {5*ps - 10, 2 * ps}, {8*ps - 10, 10}})
+
+	// Flushing an empty range does not mark any page as not modified
+	if err := m.FlushRange(0, 0, syscall.MS_SYNC); err != nil {
+		t.Fatalf("error in calling flush range :: %v", err)
+	}
+	if err := m.Evict(ps, 0, syscall.MADV_DONTNEED); err != nil {
+		t.Fatalf("error in calling evict :: %v", err)
+	}
+	checkRanges([]Range{{0, 3*ps - 10}, {5*ps - 10, 2 * ps}, {8*ps - 10, 10}})
+
+	if err := m.FlushRange(5*ps, 1, syscall.MS_SYNC); err != nil {
+		t.Fatalf("error in calling flush range :: %v", err)
+	}
+	checkRanges([]Range{{0, 3*ps - 10}, {6*ps - 10, ps}, {8*ps - 10, 10}})
+
+	if err := m.Flush(syscall.MS_SYNC); err != nil {
+		t.Fatalf("error in calling flush :: %v", err)
+	}
+	checkRanges(nil)
+
+	// Pages added by resizing are tracked too
+	if err := m.Grow(ps); err != nil {
+		t.Fatalf("error in growing :: %v", err)
+	}
+	m.WriteUint64At(5, 9*ps-8)
+	checkRanges([]Range{{9*ps - 10, 10}})
+	fileData, err := os.ReadFile(testPath)
+	if err != nil {
+		t.Fatalf("error in reading file :: %v", err)
+	}
+	if fileData[ps] != 2 || fileData[2*ps] != 3 || fileData[6*ps] != 1 || fileData[8*ps+9] != 4 {
+		t.Fatalf("modified pages are not flushed to file")
+	}
+}
+
+func TestConcurrentAccess(t *testing.T) {
+	t.Parallel()
+
+	testPath := path.Join(t.TempDir(), "m.txt")
+	m, err := Create(testPath, 1024, WithConcurrentAccess(), WithGrowthPolicy(GrowDoubling()))
+	if err != nil {
+		t.Fatalf("error in creating mapped file :: %v", err)
+	}
+
+	var writers, readers sync.WaitGroup
+	for i := range 4 {
+		writers.Add(1)
+		go func() {
+			defer writers.Done()
+			for j := range 1000 {
+				offset := int64((j*4 + i) * 8)
+				m.WriteUint64At(uint64(j), offset)
+				if j%100 == 0 {
+					if err := m.Flush(syscall.MS_SYNC); err != nil {
+						t.Errorf("error in calling flush :: %v", err)
+					}
+				}
+			}
+		}()
+	}
+
+	for range 4 {
+		readers.Add(1)
+		go func() {
+			defer readers.Done()
+			for {
+				if _, err := m.TryReadUint64At(0); err != nil {
+					if err != ErrUnmappedMemory {
+						t.Errorf("different error than expected in TryReadUint64At :: %v", err)
+					}
+					return
+				}
+				_ = m.DirtyRanges()
+			}
+		}()
+	}
+
+	writers.Wait()
+	for i := range 4 {
+		for j := range 1000 {
+			if n := m.ReadUint64At(int64((j*4 + i) * 8)); n != uint64(j) {
+				t.Fatalf("unexpected value after concurrent writes, exp: %v, actual: %v", j, n)
+			}
+		}
+	}
+
+	if err := m.Close(); err != nil {
+		t.Fatalf("error in closing mapped file :: %v", err)
+	}
+	readers.Wait()
+
+	func() {
+		defer func() {
+			if err := recover(); err != ErrUnmappedMemory {
+				t.Fatalf("different error than expected in WriteUint64At :: %v", err)
+			}
+		}()
+
+		m.WriteUint64At(0, 0)
+	}()
+
+	fileData, err := os.ReadFile(testPath)
+	if err != nil {
+		t.Fatalf("error in reading file :: %v", err)
+	}
+	if len(fileData) != 32768 {
+		t.Fatalf("unexpected file size, exp: 32768, actual: %v", len(fileData))
+	}
+}
+
+func TestCursor(t *testing.T) {
+	t.Parallel()
+
+	m, err := NewAnonymousMmap(len(testData), protPage)
+	if err != nil {
+		t.Fatalf("error in mapping :: %v", err)
+	}
+	defer func() {
+		if err := m.Unmap(); err != nil {
+			t.Fatalf("error in calling unmap :: %v", err)
+		}
+	}()
+
+	w, err := m.NewCursor(0, int64(len(testData)))
+	if err != nil {
+		t.Fatalf("error in creating cursor :: %v", err)
+	}
+	if n, err := io.Copy(w, bytes.NewReader(testData)); err != nil {
+		t.Fatalf("error in writing using cursor :: %v", err)
+	} else if n != int64(len(testData)) {
+		t.Fatalf("error in writing, exp: %v, actual: %v", len(testData), n)
+	}
+	if n, err := w.Write([]byte("a")); err != io.ErrShortWrite || n != 0 {
+		t.Fatalf("different error than expected in Write :: %v", err)
+	}
+
+	c, err := m.NewCursor(10, 10)
+	if err != nil {
+		t.Fatalf("error in creating cursor :: %v", err)
+	}
+	data, err := io.ReadAll(c)
+	if err != nil {
+		t.Fatalf("error in reading using cursor :: %v", err)
+	}
+	if !bytes.Equal(data, testData[10:20]) {
+		t.Fatalf("mapped data is not equal testData: %v, %v", data, testData[10:20])
+	}
+
+	if pos, err := c.Seek(-4, io.SeekEnd); err != nil || pos != 6 {
+		t.Fatalf("unexpected result from Seek, pos: %v, err: %v", pos, err)
+	}
+	if b, err := c.ReadByte(); err != nil || b != 'G' {
+		t.Fatalf("unexpected result from ReadByte, byte: %v, err: %v", b, err)
+	}
+	if err := c.UnreadByte(); err != nil {
+		t.Fatalf("error in calling UnreadByte :: %v", err)
+	}
+	if err := c.UnreadByte(); err == nil {
+		t.Fatalf("expected error in calling UnreadByte twice")
+	}
+
+	sb := &strings.Builder{}
+	if n, err := c.WriteTo(sb); err != nil || n != 4 {
+		t.Fatalf("unexpected result from WriteTo, n: %v, err: %v", n, err)
+	}
+	if sb.String() != "GHIJ" {
+		t.Fatalf("unexpected data written by WriteTo: %v", sb.String())
+	}
+	if _, err := c.ReadByte(); err != io.EOF {
+		t.Fatalf("different error than expected in ReadByte :: %v", err)
+	}
+	if _, err := c.Seek(-1, io.SeekStart); err == nil {
+		t.Fatalf("expected error in seeking to negative position")
+	}
+
+	// Writer that does not write the whole section
+	if _, err := c.Seek(0, io.SeekStart); err != nil {
+		t.Fatalf("error in calling Seek :: %v", err)
+	}
+	if n, err := c.WriteTo(shortWriter{}); err != io.ErrShortWrite || n != 5 {
+		t.Fatalf("unexpected result from WriteTo, n: %v, err: %v", n, err)
+	}
+
+	// Section beyond the mapped region
+	if _, err := m.NewCursor(int64(len(testData)-2), 10); !errors.Is(err, ErrIndexOutOfBound) {
+		t.Fatalf("different error than expected in NewCursor :: %v", err)
+	}
+
+	// Section cut short by shrinking the mapped region
+	c, err = m.NewCursor(int64(len(testData)-10), 10)
+	if err != nil {
+		t.Fatalf("error in creating cursor :: %v", err)
+	}
+	if err := m.Resize(int64(len(testData) - 8)); err != nil {
+		t.Fatalf("error in resizing :: %v", err)
+	}
+	if data, err := io.ReadAll(c); err != nil || string(data) != "QR" {
+		t.Fatalf("unexpected result from reading past mapped region, data: %v, err: %v", data, err)
+	}
+	if n, err := c.WriteTo(io.Discard); err != nil || n != 0 {
+		t.Fatalf("unexpected result from WriteTo, n: %v, err: %v", n, err)
+	}
+}
+
+// shortWriter writes half of the given data without returning an error.
+type shortWriter struct{}
+
+func (shortWriter) Write(p []byte) (int, error) {
+	return len(p) / 2, nil
+}
+
+func TestView(t *testing.T) {
+	t.Parallel()
+
+	testPath := path.Join(t.TempDir(), "m.txt")
+	setup(t, testPath)
+
+	m, err := Open(testPath, os.O_RDWR)
+	if err != nil {
+		t.Fatalf("error in opening mapped file :: %v", err)
+	}
+
+	v, err := m.View(10, 10)
+	if err != nil {
+		t.Fatalf("error in creating view :: %v", err)
+	}
+	if !bytes.Equal(v.Bytes(), testData[10:20]) || v.Len() != 10 {
+		t.Fatalf("view data is not equal testData: %v, %v", v.Bytes(), testData[10:20])
+	}
+	_ = m.WriteStringAt("abc", 10)
+	if string(v.Bytes()[:3]) != "abc" {
+		t.Fatalf("view does not refer to the mapped region: %v", string(v.Bytes()))
+	}
+
+	if err := m.Unmap(); err != ErrMappingInUse {
+		t.Fatalf("different error than expected in Unmap :: %v", err)
+	}
+	if err := m.Grow(10); err != ErrMappingInUse {
+		t.Fatalf("different error than expected in Grow :: %v", err)
+	}
+	if err := m.Close(); !errors.Is(err, ErrMappingInUse) {
+		t.Fatalf("different error than expected in Close :: %v", err)
+	}
+
+	v.Release()
+	v.Release()
+	if v.Bytes() != nil {
+		t.Fatalf("expected released view to not refer to the mapped region")
+	}
+	if _, err := m.View(30, 10); !errors.Is(err, ErrIndexOutOfBound) {
+		t.Fatalf("different error than expected in View :: %v", err)
+	}
+	if err := m.Close(); err != nil {
+		t.Fatalf("error in closing mapped file :: %v", err)
+	}
+	if _, err := m.View(0, 1); err != ErrUnmappedMemory {
+		t.Fatalf("different error than expected in View :: %v", err)
+	}
+}
+
+func TestGenericAccessors(t *testing.T) {
+	t.Parallel()
+
+	type record struct {
+		ID    uint64
+		Score float32
+		Valid bool
+		Tags  [3]int16
+	}
+
+	m, err := NewAnonymousMmap(4096, protPage)
+	if err != nil {
+		t.Fatalf("error in mapping :: %v", err)
+	}
+	defer func() {
+		if err := m.Unmap(); err != nil {
+			t.Fatalf("error in calling unmap :: %v", err)
+		}
+	}()
+
+	r := record{ID: 10000000000, Score: 1.5, Valid: true, Tags: [3]int16{1, -2, 3}}
+	if err := WriteValueAt(m, r, 3); err != nil {
+		t.Fatalf("error in writing value :: %v", err)
+	}
+	if m.ReadUint64At(3) != r.ID {
+		t.Fatalf("unexpected encoding of value, ID: %v", m.ReadUint64At(3))
+	}
+	if actual, err := ReadValueAt[record](m, 3); err != nil {
+		t.Fatalf("error in reading value :: %v", err)
+	} else if actual != r {
+		t.Fatalf("value read is not equal to value written, expected: %v, actual: %v", r, actual)
+	}
+
+	s, err := SliceAt[record](m, 64, 10)
+	if err != nil {
+		t.Fatalf("error in creating slice :: %v", err)
+	}
+	items := s.Items()
+	if len(items) != 10 {
+		t.Fatalf("unexpected number of items, exp: 10, actual: %v", len(items))
+	}
+	items[2] = r
+	if actual, err := ReadValueAt[record](m, 64+2*int64(unsafe.Sizeof(r))); err != nil || actual != r {
+		t.Fatalf("value read is not equal to value written in slice, actual: %v, err: %v", actual, err)
+	}
+	if err := m.Unmap(); err != ErrMappingInUse {
+		t.Fatalf("different error than expected in Unmap :: %v", err)
+	}
+	s.Release()
+	if s.Items() != nil {
+		t.Fatalf("expected released slice to not refer to the mapped region")
+	}
+
+	if _, err := SliceAt[record](m, 3, 1); !errors.Is(err, ErrUnalignedOffset) {
+		t.Fatalf("different error than expected in SliceAt :: %v", err)
+	}
+	if _, err := SliceAt[record](m, 4000, 10); !errors.Is(err, ErrIndexOutOfBound) {
+		t.Fatalf("different error than expected in SliceAt :: %v", err)
+	}
+	if _, err := SliceAt[*record](m, 0, 1); !errors.Is(err, ErrUnsupportedType) {
+		t.Fatalf("different error than expected in SliceAt :: %v", err)
+	}
+	if _, err := ReadValueAt[struct{ Name string }](m, 0); !errors.Is(err, ErrUnsupportedType) {
+		t.Fatalf("different error than expected in ReadValueAt :: %v", err)
+	}
+	if err := WriteValueAt(m, []int{1}, 0); !errors.Is(err, ErrUnsupportedType) {
+		t.Fatalf("different error than expected in WriteValueAt :: %v", err)
+	}
+	if _, err := SliceAt[struct{}](m, 0, 1); !errors.Is(err, ErrUnsupportedType) {
+		t.Fatalf("different error than expected in SliceAt :: %v", err)
+	}
+	if _, err := ReadValueAt[[0]uint64](m, 0); !errors.Is(err, ErrUnsupportedType) {
+		t.Fatalf("different error than expected in ReadValueAt :: %v", err)
+	}
+	if err := WriteValueAt(m, r, 4090); !errors.Is(err, ErrIndexOutOfBound) {
+		t.Fatalf("different error than expected in WriteValueAt :: %v", err)
+	}
+}
+
+func TestPrefixedAccessors(t *testing.T) {
+	t.Parallel()
+
+	m, err := NewAnonymousMmap(256, protPage)
+	if err != nil {
+		t.Fatalf("error in mapping :: %v", err)
+	}
+	defer func() {
+		if err := m.Unmap(); err != nil {
+			t.Fatalf("error in calling unmap :: %v", err)
+		}
+	}()
+
+	records := []string{"", "a", strings.Repeat("b", 200)}
+	for _, prefix := range []Prefix{PrefixUvarint, PrefixUint32} {
+		offset := int64(0)
+		for i, r := range records {
+			if i%2 == 0 {
+				offset += int64(m.WriteStringPrefixedAt(r, offset, prefix))
+			} else {
+				offset += int64(m.WriteBytesPrefixedAt([]byte(r), offset, prefix))
+			}
+		}
+
+		offset = 0
+		for i, r := range records {
+			var n int
+			if i%2 == 0 {
+				var s string
+				s, n = m.ReadStringPrefixedAt(offset, prefix)
+				if s != r {
+					t.Fatalf("string read is not equal to string written: %v, %v", s, r)
+				}
+			} else {
+				var b []byte
+				b, n = m.ReadBytesPrefixedAt(offset, prefix)
+				if string(b) != r {
+					t.Fatalf("bytes read are not equal to bytes written: %v, %v", b, r)
+				}
+			}
+			offset += int64(n)
+		}
+		if prefix == PrefixUvarint && offset != 1+2+2+200 {
+			t.Fatalf("unexpected number of bytes consumed, exp: 205, actual: %v", offset)
+		}
+		if prefix == PrefixUint32 && offset != 12+201 {
+			t.Fatalf("unexpected number of bytes consumed, exp: 213, actual: %v", offset)
+		}
+	}
+
+	expectPanic := func(expected error, fn func()) {
+		t.Helper()
+		defer func() {
+			if err := recover(); err != expected {
+				t.Fatalf("different error than expected :: %v", err)
+			}
+		}()
+
+		fn()
+	}
+
+	// Record does not fit in the mapped region
+	expectPanic(ErrIndexOutOfBound, func() { _ = m.WriteStringPrefixedAt("abc", 253, PrefixUvarint) })
+	// Length header larger than the mapped region
+	m.WriteUint32At(1000, 0)
+	expectPanic(ErrIndexOutOfBound, func() { _, _ = m.ReadBytesPrefixedAt(0, PrefixUint32) })
+	// Varint running past the mapped region
+	m.WriteUint8At(0x80, 255)
+	expectPanic(ErrIndexOutOfBound, func() { _, _ = m.ReadStringPrefixedAt(255, PrefixUvarint) })
+	// Varint overflowing uint64
+	_, _ = m.WriteAt(bytes.Repeat([]byte{0xff}, 11), 0)
+	expectPanic(ErrVarintOverflow, func() { _, _ = m.ReadStringPrefixedAt(0, PrefixUvarint) })
+}
+
+func TestVarintAccessors(t *testing.T) {
+	t.Parallel()
+
+	m, err := NewAnonymousMmap(32, protPage)
+	if err != nil {
+		t.Fatalf("error in mapping :: %v", err)
+	}
+	defer func() {
+		if err := m.Unmap(); err != nil {
+			t.Fatalf("error in calling unmap :: %v", err)
+		}
+	}()
+
+	if n := m.WriteUvarintAt(300, 0); n != 2 {
+		t.Fatalf("unexpected number of bytes written, exp: 2, actual: %v", n)
+	}
+	if n := m.WriteVarintAt(-65, 2); n != 2 {
+		t.Fatalf("unexpected number of bytes written, exp: 2, actual: %v", n)
+	}
+	if n := m.WriteUvarintAt(1<<63, 4); n != 10 {
+		t.Fatalf("unexpected number of bytes written, exp: 10, actual: %v", n)
+	}
+	if num, n := m.ReadUvarintAt(0); num != 300 || n != 2 {
+		t.Fatalf("unexpected result from ReadUvarintAt, num: %v, n: %v", num, n)
+	}
+	if num, n := m.ReadVarintAt(2); num != -65 || n != 2 {
+		t.Fatalf("unexpected result from ReadVarintAt, num: %v, n: %v", num, n)
+	}
+	if num, n := m.ReadUvarintAt(4); num != 1<<63 || n != 10 {
+		t.Fatalf("unexpected result from ReadUvarintAt, num: %v, n: %v", num, n)
+	}
+	expected := []byte{0xac, 0x02, 0x81, 0x01}
+	actual := make([]byte, 4)
+	if _, err := m.ReadAt(actual, 0); err != nil || !bytes.Equal(expected, actual) {
+		t.Fatalf("unexpected varint encoding, expected: %v, actual: %v", expected, actual)
+	}
+
+	func() {
+		defer func() {
+			if err := recover(); err != ErrIndexOutOfBound {
+				t.Fatalf("different error than expected in WriteUvarintAt :: %v", err)
+			}
+		}()
+
+		_ = m.WriteUvarintAt(1<<63, 28)
+	}()
+	m.WriteUint16At(0x8080, 30)
+	func() {
+		defer func() {
+			if err := recover(); err != ErrIndexOutOfBound {
+				t.Fatalf("different error than expected in ReadVarintAt :: %v", err)
+			}
+		}()
+
+		_, _ = m.ReadVarintAt(30)
+	}()
+	_, _ = m.WriteAt(bytes.Repeat([]byte{0xff}, 11), 0)
+	func() {
+		defer func() {
+			if err := recover(); err != ErrVarintOverflow {
+				t.Fatalf("different error than expected in ReadUvarintAt :: %v", err)
+			}
+		}()
+
+		_, _ = m.ReadUvarintAt(0)
+	}()
+}
+
+func TestResident(t *testing.T) {
+	t.Parallel()
+
+	ps := int64(os.Getpagesize())
+	m, err := NewAnonymousMmap(int(4*ps), protPage)
+	if err != nil {
+		t.Fatalf("error in mapping :: %v", err)
+	}
+	defer func() {
+		if err := m.Unmap(); err != nil {
+			t.Fatalf("error in calling unmap :: %v", err)
+		}
+	}()
+
+	// Anonymous pages become resident on first write
+	m.WriteUint8At(1, 0)
+	m.WriteUint8At(1, 2*ps)
+	resident, err := m.Resident(0, 4*ps)
+	if err != nil {
+		t.Fatalf("error in calling resident :: %v", err)
+	}
+	if len(resident) != 4 || !resident[0] || resident[1] || !resident[2] || resident[3] {
+		t.Fatalf("unexpected resident pages: %v", resident)
+	}
+
+	if ratio, err := m.ResidentRatio(0, 4*ps); err != nil || ratio != 0.5 {
+		t.Fatalf("unexpected resident ratio, ratio: %v, err: %v", ratio, err)
+	}
+	if resident, err := m.Resident(ps-1, 2); err != nil || len(resident) != 2 {
+		t.Fatalf("unexpected resident pages, resident: %v, err: %v", resident, err)
+	}
+	if _, err := m.Resident(0, 5*ps); !errors.Is(err, ErrIndexOutOfBound) {
+		t.Fatalf("different error than expected in Resident :: %v", err)
+	}
+}
+
+func TestPrefetchEvict(t *testing.T) {
+	t.Parallel()
+
+	testPath := path.Join(t.TempDir(), "m.txt")
+	size := int64(3*prefetchChunk + 100)
+
+	m, err := Create(testPath, size)
+	if err != nil {
+		t.Fatalf("error in creating mapped file :: %v", err)
+	}
+	defer func() {
+		if err := m.Close(); err != nil {
+			t.Fatalf("error in closing mapped file :: %v", err)
+		}
+	}()
+
+	var calls, lastDone int64
+	err = m.Prefetch(0, size, 2, func(done, total int64) {
+		calls++
+		if done <= lastDone || total != size {
+			t.Errorf("unexpected progress, done: %v, last done: %v, total: %v", done, lastDone, total)
+		}
+		lastDone = done
+	})
+	if err != nil {
+		t.Fatalf("error in calling prefetch :: %v", err)
+	}
+	if calls != 4 || lastDone != size {
+		t.Fatalf("unexpected progress calls, calls: %v, done: %v", calls, lastDone)
+	}
+	if ratio, err := m.ResidentRatio(0, size); err != nil || ratio != 1 {
+		t.Fatalf("unexpected resident ratio after prefetch, ratio: %v, err: %v", ratio, err)
+	}
+
+	m.WriteUint64At(10000000000, 100)
+	if err := m.Evict(0, prefetchChunk, syscall.MADV_DONTNEED); err != nil {
+		t.Fatalf("error in calling evict :: %v", err)
+	}
+	if len(m.DirtyRanges()) != 0 {
+		t.Fatalf("expected evicted pages to be flushed")
+	}
+	if m.ReadUint64At(100) != 10000000000 {
+		t.Fatalf("data lost after evicting pages of shared file mapping")
+	}
+
+	if err := m.Prefetch(size, 1, 0, nil); !errors.Is(err, ErrIndexOutOfBound) {
+		t.Fatalf("different error than expected in Prefetch :: %v", err)
+	}
+	if err := m.Evict(-1, 1, syscall.MADV_DONTNEED); !errors.Is(err, ErrIndexOutOfBound) {
+		t.Fatalf("different error than expected in Evict :: %v", err)
+	}
+}
+
+func TestProtect(t *testing.T) {
+	t.Parallel()
+
+	ps := int64(os.Getpagesize())
+	m, err := NewAnonymousMmap(int(4*ps), protPage)
+	if err != nil {
+		t.Fatalf("error in mapping :: %v", err)
+	}
+	defer func() {
+		if err := m.Unmap(); err != nil {
+			t.Fatalf("error in calling unmap :: %v", err)
+		}
+	}()
+
+	expectPanic := func(expected error, fn func()) {
+		t.Helper()
+		defer func() {
+			if err := recover(); err != expected {
+				t.Fatalf("different error than expected :: %v", err)
+			}
+		}()
+
+		fn()
+	}
+
+	m.WriteUint64At(10000000000, ps)
+	if err := m.ProtectRange(ps, 1, syscall.PROT_READ); err != nil {
+		t.Fatalf("error in calling protect range :: %v", err)
+	}
+	if m.ReadUint64At(ps) != 10000000000 {
+		t.Fatalf("value read is not equal to value written")
+	}
+	expectPanic(ErrReadOnly, func() { m.WriteUint64At(0, ps) })
+	expectPanic(ErrReadOnly, func() { _, _ = m.WriteAt(make([]byte, 10), ps-5) })
+	expectPanic(ErrReadOnly, func() { m.AtomicStoreUint64At(0, 2*ps-8) })
+	if err := m.TryWriteUint64At(0, ps); err != ErrReadOnly {
+		t.Fatalf("different error than expected in TryWriteUint64At :: %v", err)
+	}
+	if err := WriteValueAt(m, uint16(1), ps); err != ErrReadOnly {
+		t.Fatalf("different error than expected in WriteValueAt :: %v", err)
+	}
+	if err := m.Grow(ps); err != ErrNotResizable {
+		t.Fatalf("different error than expected in Grow :: %v", err)
+	}
+
+	// Other pages are still writable
+	_, _ = m.WriteAt(make([]byte, 5), ps-5)
+	m.WriteUint64At(1, 2*ps)
+
+	if err := m.Protect(syscall.PROT_READ); err != nil {
+		t.Fatalf("error in calling protect :: %v", err)
+	}
+	expectPanic(ErrReadOnly, func() { m.WriteUint8At(0, 0) })
+	if err := m.Grow(ps); err != nil {
+		t.Fatalf("error in growing :: %v", err)
+	}
+	expectPanic(ErrReadOnly, func() { m.WriteUint8At(0, 4*ps) })
+
+	if err := m.Protect(protPage); err != nil {
+		t.Fatalf("error in calling protect :: %v", err)
+	}
+	m.WriteUint64At(2, ps)
+	m.WriteUint8At(1, 4*ps)
+	if m.ReadUint64At(ps) != 2 || m.ReadUint8At(4*ps) != 1 {
+		t.Fatalf("value read is not equal to value written")
+	}
+	if err := m.ProtectRange(5*ps, 1, syscall.PROT_READ); !errors.Is(err, ErrIndexOutOfBound) {
+		t.Fatalf("different error than expected in ProtectRange :: %v", err)
+	}
+
+	// Empty range does not change the protection of any page
+	if err := m.ProtectRange(0, 0, syscall.PROT_READ); err != nil {
+		t.Fatalf("error in calling protect range :: %v", err)
+	}
+	m.WriteUint8At(3, 0)
+	if err := m.Grow(ps); err != nil {
+		t.Fatalf("error in growing :: %v", err)
+	}
+
+	// Read only mappings report ErrReadOnly instead of crashing
+	ro, err := NewAnonymousMmap(int(ps), syscall.PROT_READ)
+	if err != nil {
+		t.Fatalf("error in mapping :: %v", err)
+	}
+	expectPanic(ErrReadOnly, func() { _ = ro.WriteStringAt("a", 0) })
+	if err := ro.Unmap(); err != nil {
+		t.Fatalf("error in calling unmap :: %v", err)
+	}
+}
+
+func TestTruncatedFile(t *testing.T) {
+	t.Parallel()
+
+	ps := int64(os.Getpagesize())
+	m, err := Create(path.Join(t.TempDir(), "m.dat"), 4*ps, WithFaultRecovery())
+	if err != nil {
+		t.Fatalf("error in creating mapped file :: %v", err)
+	}
+	defer func() {
+		if err := m.Close(); err != nil {
+			t.Fatalf("error in calling close :: %v", err)
+		}
+	}()
+
+	m.WriteUint64At(10000000000, 3*ps)
+	if err := m.Flush(syscall.MS_SYNC); err != nil {
+		t.Fatalf("error in calling flush :: %v", err)
+	}
+	if err := m.file.Truncate(ps); err != nil {
+		t.Fatalf("error in truncating file :: %v", err)
+	}
+
+	func() {
+		defer func() {
+			if err := recover(); err != ErrIndexOutOfBound {
+				t.Fatalf("different error than expected :: %v", err)
+			}
+		}()
+
+		m.ReadUint64At(3 * ps)
+	}()
+	if _, err := m.TryReadUint64At(3 * ps); !errors.Is(err, ErrIndexOutOfBound) {
+		t.Fatalf("different error than expected in TryReadUint64At :: %v", err)
+	}
+	if _, err := ReadValueAt[uint32](m, 2*ps); !errors.Is(err, ErrIndexOutOfBound) {
+		t.Fatalf("different error than expected in ReadValueAt :: %v", err)
+	}
+
+	if err := m.Refresh(); err != nil {
+		t.Fatalf("error in calling refresh :: %v", err)
+	}
+	if m.ValidLength() != ps {
+		t.Fatalf("valid length %d, expected %d", m.ValidLength(), ps)
+	}
+	if _, err := m.TryReadUint64At(ps); !errors.Is(err, ErrIndexOutOfBound) {
+		t.Fatalf("different error than expected in TryReadUint64At :: %v", err)
+	}
+	m.WriteUint64At(1, ps-8)
+	if n, err := m.ReadAt(make([]byte, 2*ps), 0); err != nil || n != int(ps) {
+		t.Fatalf("unexpected result from ReadAt, n: %v, err: %v", n, err)
+	}
+
+	if err := m.file.Truncate(8 * ps); err != nil {
+		t.Fatalf("error in extending file :: %v", err)
+	}
+	if err := m.Refresh(); err != nil {
+		t.Fatalf("error in calling refresh :: %v", err)
+	}
+	if m.ValidLength() != 4*ps {
+		t.Fatalf("valid length %d, expected %d", m.ValidLength(), 4*ps)
+	}
+	if m.ReadUint64At(3*ps) != 0 || m.ReadUint64At(ps-8) != 1 {
+		t.Fatalf("value read is not equal to value written")
+	}
+
+	// Resizing and protection apply to the whole mapped region
+	if err := m.file.Truncate(100); err != nil {
+		t.Fatalf("error in truncating file :: %v", err)
+	}
+	if err := m.Refresh(); err != nil {
+		t.Fatalf("error in calling refresh :: %v", err)
+	}
+	if err := m.Protect(syscall.PROT_READ); err != nil {
+		t.Fatalf("error in calling protect :: %v", err)
+	}
+	if m.readOnlyPages != 4 {
+		t.Fatalf("read only pages %d, expected 4", m.readOnlyPages)
+	}
+	if err := m.Protect(protPage); err != nil {
+		t.Fatalf("error in calling protect :: %v", err)
+	}
+	if err := m.Grow(10); err != nil {
+		t.Fatalf("error in growing :: %v", err)
+	}
+	if m.ValidLength() != 4*ps+10 || len(m.data) != int(4*ps+10) {
+		t.Fatalf("valid length %d and mapped length %d, expected %d", m.ValidLength(), len(m.data), 4*ps+10)
+	}
+
+	a, err := NewAnonymousMmap(int(ps), protPage)
+	if err != nil {
+		t.Fatalf("error in mapping :: %v", err)
+	}
+	if err := a.Refresh(); err != nil || a.ValidLength() != ps {
+		t.Fatalf("error in calling refresh on anonymous mapping :: %v", err)
+	}
+	if err := a.Unmap(); err != nil {
+		t.Fatalf("error in calling unmap :: %v", err)
+	}
+	if err := a.Refresh(); err != ErrUnmappedMemory {
+		t.Fatalf("different error than expected in Refresh :: %v", err)
+	}
+}
+
+func TestSegmentedFile(t *testing.T) {
+	t.Parallel()
+
+	ps := int64(os.Getpagesize())
+	dir := path.Join(t.TempDir(), "segments")
+	s, err := OpenSegmented(dir, ps, WithConcurrentAccess())
+	if err != nil {
+		t.Fatalf("error in opening segmented file :: %v", err)
+	}
+	if s.Size() != 0 {
+		t.Fatalf("size of empty segmented file %d, expected 0", s.Size())
+	}
+
+	// Writes spanning the end of a segment roll over to a new segment
+	if n, err := s.WriteAt(testData, ps-10); err != nil || n != len(testData) {
+		t.Fatalf("error in writing to segmented file, wrote %d bytes :: %v", n, err)
+	}
+	s.WriteUint64At(10000000000, 2*ps-4)
+	s.WriteUint64At(20000000000, 3*ps+8)
+	if s.Size() != 4*ps {
+		t.Fatalf("size of segmented file %d, expected %d", s.Size(), 4*ps)
+	}
+
+	buf := make([]byte, len(testData))
+	if n, _ := s.ReadAt(buf, ps-10); n != len(testData) || !bytes.Equal(buf, testData) {
+		t.Fatalf("data read is not equal to data written")
+	}
+	if n, _ := s.ReadAt(make([]byte, 20), 4*ps-10); n != 10 {
+		t.Fatalf("number of bytes read %d, expected 10", n)
+	}
+	if s.ReadUint64At(2*ps-4) != 10000000000 || s.ReadUint64At(3*ps+8) != 20000000000 {
+		t.Fatalf("value read is not equal to value written")
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("error in closing segmented file :: %v", err)
+	}
+
+	// Reopen and delete old segments
+	s, err = OpenSegmented(dir, ps)
+	if err != nil {
+		t.Fatalf("error in opening segmented file :: %v", err)
+	}
+	if s.Size() != 4*ps || s.ReadUint64At(2*ps-4) != 10000000000 {
+		t.Fatalf("segmented file not equal after reopening")
+	}
+	if err := s.DeleteBefore(2*ps + 1); err != nil {
+		t.Fatalf("error in deleting segments :: %v", err)
+	}
+	if s.Start() != 2*ps || s.Size() != 4*ps {
+		t.Fatalf("start %d and size %d after deleting segments", s.Start(), s.Size())
+	}
+	if _, err := os.Stat(path.Join(dir, "seg-000002.dat")); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("segment not deleted :: %v", err)
+	}
+	func() {
+		defer func() {
+			if err := recover(); err != ErrIndexOutOfBound {
+				t.Fatalf("different error than expected :: %v", err)
+			}
+		}()
+
+		s.ReadUint64At(2*ps - 4)
+	}()
+	func() {
+		defer func() {
+			if err := recover(); err != ErrIndexOutOfBound {
+				t.Fatalf("different error than expected :: %v", err)
+			}
+		}()
+
+		s.ReadUint64At(math.MaxInt64 - 4)
+	}()
+	if s.ReadUint64At(3*ps+8) != 20000000000 {
+		t.Fatalf("value read is not equal to value written")
+	}
+	if err := s.DeleteBefore(10 * ps); err != nil || s.Start() != 3*ps {
+		t.Fatalf("last segment deleted :: %v", err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("error in closing segmented file :: %v", err)
+	}
+
+	s, err = OpenSegmented(dir, ps)
+	if err != nil {
+		t.Fatalf("error in opening segmented file :: %v", err)
+	}
+	if s.Start() != 3*ps || s.Size() != 4*ps || s.ReadUint64At(3*ps+8) != 20000000000 {
+		t.Fatalf("segmented file not equal after reopening")
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("error in closing segmented file :: %v", err)
+	}
+
+	// Segments must be contiguous
+	if err := os.WriteFile(path.Join(dir, "seg-000006.dat"), nil, 0644); err != nil {
+		t.Fatalf("error in writing file :: %v", err)
+	}
+	if _, err := OpenSegmented(dir, ps); err == nil {
+		t.Fatalf("no error in opening segmented file with a missing segment")
+	}
+}
+
+func TestRingBuffer(t *testing.T) {
+	t.Parallel()
+
+	ps := os.Getpagesize()
+	testPath := path.Join(t.TempDir(), "m.ring")
+	if _, err := OpenRingBuffer(testPath, ps+1); !errors.Is(err, ErrInvalidCapacity) {
+		t.Fatalf("different error than expected in OpenRingBuffer :: %v", err)
+	}
+
+	r, err := OpenRingBuffer(testPath, ps)
+	if err != nil {
+		t.Fatalf("error in opening ring buffer :: %v", err)
+	}
+	if r.Cap() != ps || r.Len() != 0 || len(r.Readable()) != 0 {
+		t.Fatalf("new ring buffer is not empty")
+	}
+
+	data := bytes.Repeat(testData, ps/len(testData)+1)
+	if n, err := r.Write(data[:ps-10]); err != nil || n != ps-10 {
+		t.Fatalf("error in writing to ring buffer :: %v", err)
+	}
+	buf := make([]byte, ps)
+	if n, err := r.Read(buf); err != nil || n != ps-10 || !bytes.Equal(buf[:n], data[:ps-10]) {
+		t.Fatalf("data read is not equal to data written :: %v", err)
+	}
+	if n, err := r.Read(buf); err != nil || n != 0 {
+		t.Fatalf("data read from empty ring buffer :: %v", err)
+	}
+
+	// Writes and reads across the end of the buffer are contiguous
+	if n, err := r.Write(testData); err != nil || n != len(testData) {
+		t.Fatalf("error in writing to ring buffer :: %v", err)
+	}
+	if !bytes.Equal(r.Readable(), testData) {
+		t.Fatalf("data read is not equal to data written")
+	}
+	if n, err := r.Write(data); err != io.ErrShortWrite || n != ps-len(testData) {
+		t.Fatalf("different error than expected in Write, wrote %d bytes :: %v", n, err)
+	}
+	if len(r.Writable()) != 0 || r.Len() != ps {
+		t.Fatalf("ring buffer is not full")
+	}
+	for _, f := range []func(){func() { r.Commit(1) }, func() { r.Consume(ps + 1) }, func() { r.Consume(-1) }} {
+		func() {
+			defer func() {
+				if err := recover(); err != ErrIndexOutOfBound {
+					t.Fatalf("different error than expected in Commit or Consume :: %v", err)
+				}
+			}()
+
+			f()
+		}()
+	}
+	r.Consume(len(testData))
+	if err := r.Close(); err != nil {
+		t.Fatalf("error in closing ring buffer :: %v", err)
+	}
+
+	// Reopen the ring buffer
+	if _, err := OpenRingBuffer(testPath, 2*ps); !errors.Is(err, ErrInvalidCapacity) {
+		t.Fatalf("different error than expected in OpenRingBuffer :: %v", err)
+	}
+	r, err = OpenRingBuffer(testPath, ps)
+	if err != nil {
+		t.Fatalf("error in opening ring buffer :: %v", err)
+	}
+	if !bytes.Equal(r.Readable(), data[:ps-len(testData)]) {
+		t.Fatalf("data read is not equal to data written")
+	}
+	r.Consume(r.Len())
+
+	// Producer and consumer running concurrently
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for written := 0; written < 100*len(data); {
+			w := r.Writable()
+			n := copy(w, data[written%len(data):])
+			r.Commit(n)
+			written += n
+			if n == 0 {
+				runtime.Gosched()
+			}
+		}
+	}()
+
+	read := make([]byte, 0, 100*len(data))
+	for len(read) < cap(read) {
+		b := r.Readable()
+		read = append(read, b...)
+		r.Consume(len(b))
+		if len(b) == 0 {
+			runtime.Gosched()
+		}
+	}
+	wg.Wait()
+	if !bytes.Equal(read, bytes.Repeat(data, 100)) {
+		t.Fatalf("data read is not equal to data written")
+	}
+
+	if err := r.Close(); err != nil {
+		t.Fatalf("error in closing ring buffer :: %v", err)
+	}
+	if _, err := r.Write(testData); err != ErrUnmappedMemory {
+		t.Fatalf("different error than expected in Write :: %v", err)
+	}
+	if r.Len() != 0 || r.Writable() != nil || r.Readable() != nil {
+		t.Fatalf("closed ring buffer is not empty")
+	}
+	func() {
+		defer func() {
+			if err := recover(); err != ErrUnmappedMemory {
+				t.Fatalf("different error than expected in Commit :: %v", err)
+			}
+		}()
+
+		r.Commit(1)
+	}()
+
+	// Head past the tail is rejected
+	f, err := os.OpenFile(testPath, os.O_RDWR, 0)
+	if err != nil {
+		t.Fatalf("error in opening file :: %v", err)
+	}
+	if _, err := f.WriteAt(append([]byte{5}, make([]byte, 15)...), 0); err != nil {
+		t.Fatalf("error in writing to file :: %v", err)
+	}
+	if _, err := NewRingBuffer(f, ps); !errors.Is(err, ErrIndexOutOfBound) {
+		t.Fatalf("different error than expected in NewRingBuffer :: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatalf("error in closing file :: %v", err)
+	}
+}
+
+func TestWaitWake(t *testing.T) {
+	t.Parallel()
+
+	m, err := NewSharedAnonymousMmap(os.Getpagesize(), protPage)
+	if err != nil {
+		t.Fatalf("error in mapping :: %v", err)
+	}
+	defer func() {
+		if err := m.Unmap(); err != nil {
+			t.Fatalf("error in calling unmap :: %v", err)
+		}
+	}()
+
+	if err := m.WaitUint32At(1, 8, -1); err != nil {
+		t.Fatalf("error in waiting on a different value :: %v", err)
+	}
+	if err := m.WaitUint32At(0, 8, 10*time.Millisecond); !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Fatalf("different error than expected in WaitUint32At :: %v", err)
+	}
+
+	done := make(chan error)
+	go func() {
+		for m.AtomicLoadUint32At(8) == 0 {
+			if err := m.WaitUint32At(0, 8, -1); err != nil {
+				done <- err
+				return
+			}
+		}
+		done <- nil
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	m.AtomicStoreUint32At(1, 8)
+	if _, err := m.WakeUint32At(8, 1); err != nil {
+		t.Fatalf("error in waking up :: %v", err)
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("error in waiting :: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("waiter not woken up")
+	}
+
+	func() {
+		defer func() {
+			if err := recover(); err != ErrUnalignedOffset {
+				t.Fatalf("different error than expected :: %v", err)
+			}
+		}()
+
+		_ = m.WaitUint32At(0, 2, 0)
+	}()
+}
+
+// deadPID returns PID of a process that has exited.
+func deadPID(t *testing.T) uint32 {
+	t.Helper()
+
+	cmd := exec.Command("true")
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("error in running process :: %v", err)
+	}
+
+	return uint32(cmd.Process.Pid)
+}
+
+func TestSharedMutex(t *testing.T) {
+	t.Parallel()
+
+	m, err := NewSharedAnonymousMmap(os.Getpagesize(), protPage)
+	if err != nil {
+		t.Fatalf("error in mapping :: %v", err)
+	}
+	defer func() {
+		if err := m.Unmap(); err != nil {
+			t.Fatalf("error in calling unmap :: %v", err)
+		}
+	}()
+
+	mu := NewSharedMutex(m, 0)
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 1000 {
+				if err := mu.Lock(); err != nil {
+					t.Errorf("error in locking :: %v", err)
+					return
+				}
+				m.WriteUint64At(m.ReadUint64At(64)+1, 64)
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if m.ReadUint64At(64) != 4000 {
+		t.Fatalf("counter %d, expected 4000", m.ReadUint64At(64))
+	}
+
+	if locked, err := mu.TryLock(); !locked || err != nil {
+		t.Fatalf("error in calling try lock :: %v", err)
+	}
+	if locked, err := mu.TryLock(); locked || err != nil {
+		t.Fatalf("locked mutex locked again :: %v", err)
+	}
+	mu.Unlock()
+	func() {
+		defer func() {
+			if err := recover(); err == nil {
+				t.Fatalf("no panic in unlocking unlocked mutex")
+			}
+		}()
+
+		mu.Unlock()
+	}()
+
+	// Lock held by a dead process is taken over
+	m.AtomicStoreUint32At(deadPID(t), 0)
+	if err := mu.Lock(); err != ErrOwnerDead {
+		t.Fatalf("different error than expected in Lock :: %v", err)
+	}
+	mu.Unlock()
+	m.AtomicStoreUint32At(deadPID(t), 0)
+	if locked, err := mu.TryLock(); !locked || err != ErrOwnerDead {
+		t.Fatalf("different error than expected in TryLock :: %v", err)
+	}
+	mu.Unlock()
+
+	// Condition variable
+	cond := NewSharedCond(m, 4)
+	done := make(chan error)
+	go func() {
+		done <- func() error {
+			if err := mu.Lock(); err != nil {
+				return err
+			}
+			defer mu.Unlock()
+
+			for m.ReadUint64At(72) == 0 {
+				if err := cond.Wait(mu, -1); err != nil {
+					return err
+				}
+			}
+			return nil
+		}()
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	if err := mu.Lock(); err != nil {
+		t.Fatalf("error in locking :: %v", err)
+	}
+	m.WriteUint64At(1, 72)
+	cond.Broadcast()
+	mu.Unlock()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("error in waiting :: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("waiter not woken up")
+	}
+
+	if err := mu.Lock(); err != nil {
+		t.Fatalf("error in locking :: %v", err)
+	}
+	if err := cond.Wait(mu, 10*time.Millisecond); !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Fatalf("different error than expected in Wait :: %v", err)
+	}
+	if locked, _ := mu.TryLock(); locked {
+		t.Fatalf("mutex not locked after Wait")
+	}
+	mu.Unlock()
+}
+
+func TestSharedRWMutex(t *testing.T) {
+	t.Parallel()
+
+	m, err := NewSharedAnonymousMmap(os.Getpagesize(), protPage)
+	if err != nil {
+		t.Fatalf("error in mapping :: %v", err)
+	}
+	defer func() {
+		if err := m.Unmap(); err != nil {
+			t.Fatalf("error in calling unmap :: %v", err)
+		}
+	}()
+
+	rw := NewSharedRWMutex(m, 0)
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 500 {
+				if i%2 == 0 {
+					if err := rw.Lock(); err != nil {
+						t.Errorf("error in locking :: %v", err)
+						return
+					}
+					m.WriteUint64At(m.ReadUint64At(64)+1, 64)
+					m.WriteUint64At(m.ReadUint64At(72)+1, 72)
+					rw.Unlock()
+					continue
+				}
+
+				if err := rw.RLock(); err != nil {
+					t.Errorf("error in read locking :: %v", err)
+					return
+				}
+				if m.ReadUint64At(64) != m.ReadUint64At(72) {
+					t.Errorf("data read while locked for writing")
+				}
+				rw.RUnlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if m.ReadUint64At(64) != 2000 {
+		t.Fatalf("counter %d, expected 2000", m.ReadUint64At(64))
+	}
+
+	if err := rw.RLock(); err != nil {
+		t.Fatalf("error in read locking :: %v", err)
+	}
+	if err := rw.RLock(); err != nil {
+		t.Fatalf("error in read locking :: %v", err)
+	}
+	rw.RUnlock()
+	rw.RUnlock()
+	func() {
+		defer func() {
+			if err := recover(); err == nil {
+				t.Fatalf("no panic in unlocking unlocked mutex")
+			}
+		}()
+
+		rw.RUnlock()
+	}()
+
+	// Lock held by a dead writer is taken over
+	m.AtomicStoreUint32At(lockWriter|deadPID(t), 0)
+	if err := rw.RLock(); err != ErrOwnerDead {
+		t.Fatalf("different error than expected in RLock :: %v", err)
+	}
+	rw.RUnlock()
+	m.AtomicStoreUint32At(lockWriter|deadPID(t), 0)
+	if err := rw.Lock(); err != ErrOwnerDead {
+		t.Fatalf("different error than expected in Lock :: %v", err)
+	}
+	rw.Unlock()
+	if m.AtomicLoadUint32At(0) != 0 {
+		t.Fatalf("mutex not unlocked")
+	}
+}
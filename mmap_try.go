@@ -0,0 +1,147 @@
+package mmap
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+// checkBounds returns an error if m.data is nil or numBytes cannot be read
+// or written in the mapped file starting at given offset. Returned errors
+// wrap ErrUnmappedMemory or ErrIndexOutOfBound.
+func (m *File) checkBounds(offset, numBytes int64) error {
+	if m.data == nil {
+		return ErrUnmappedMemory
+	} else if offset < 0 || numBytes < 0 || offset > m.length-numBytes {
+		return fmt.Errorf("%w: offset %d, length %d, mapped length %d",
+			ErrIndexOutOfBound, offset, numBytes, m.length)
+	}
+
+	return nil
+}
+
+// TryReadAt is same as ReadAt except that it returns an
+// error instead of panicking when offset is invalid.
+func (m *File) TryReadAt(dest []byte, offset int64) (int, error) {
+	m.rlock()
+	defer m.runlock()
+
+	if err := m.checkBounds(offset, 1); err != nil {
+		return 0, err
+	}
+
+	var n int
+	err := m.checkFault(func() {
+		n = copy(dest, m.data[offset:m.length])
+	})
+	return n, err
+}
+
+// TryWriteAt is same as WriteAt except that it returns an
+// error instead of panicking when offset is invalid.
+func (m *File) TryWriteAt(src []byte, offset int64) (int, error) {
+	if err := m.autoGrow(offset, int64(len(src))); err != nil {
+		return 0, err
+	}
+
+	m.rlock()
+	defer m.runlock()
+
+	if err := m.checkBounds(offset, 1); err != nil {
+		return 0, err
+	}
+	if err := m.checkReadOnly(offset, min(int64(len(src)), m.length-offset)); err != nil {
+		return 0, err
+	}
+
+	var n int
+	err := m.checkFault(func() {
+		n = copy(m.data[offset:m.length], src)
+	})
+	m.markDirty(offset, int64(n))
+	return n, err
+}
+
+// TryReadStringAt is same as ReadStringAt except that it returns
+// an error instead of panicking when offset is invalid.
+func (m *File) TryReadStringAt(dest *strings.Builder, offset, maxLength int64) (int, error) {
+	m.rlock()
+	defer m.runlock()
+
+	if err := m.checkBounds(offset, 1); err != nil {
+		return 0, err
+	}
+
+	dataLength := min(m.length-offset, int64(dest.Cap()-dest.Len()), maxLength)
+	var n int
+	err := m.checkFault(func() {
+		n, _ = dest.Write(m.data[offset : offset+dataLength])
+	})
+	return n, err
+}
+
+// TryWriteStringAt is same as WriteStringAt except that it returns
+// an error instead of panicking when offset is invalid.
+func (m *File) TryWriteStringAt(src string, offset int64) (int, error) {
+	if err := m.autoGrow(offset, int64(len(src))); err != nil {
+		return 0, err
+	}
+
+	m.rlock()
+	defer m.runlock()
+
+	if err := m.checkBounds(offset, 1); err != nil {
+		return 0, err
+	}
+	if err := m.checkReadOnly(offset, min(int64(len(src)), m.length-offset)); err != nil {
+		return 0, err
+	}
+
+	var n int
+	err := m.checkFault(func() {
+		n = copy(m.data[offset:m.length], src)
+	})
+	m.markDirty(offset, int64(n))
+	return n, err
+}
+
+// TryReadUint64At is same as ReadUint64At except that it returns
+// an error instead of panicking when offset is invalid.
+func (m *File) TryReadUint64At(offset int64) (uint64, error) {
+	m.rlock()
+	defer m.runlock()
+
+	if err := m.checkBounds(offset, 8); err != nil {
+		return 0, err
+	}
+
+	var num uint64
+	err := m.checkFault(func() {
+		num = binary.LittleEndian.Uint64(m.data[offset : offset+8])
+	})
+	return num, err
+}
+
+// TryWriteUint64At is same as WriteUint64At except that it returns
+// an error instead of panicking when offset is invalid.
+func (m *File) TryWriteUint64At(num uint64, offset int64) error {
+	if err := m.autoGrow(offset, 8); err != nil {
+		return err
+	}
+
+	m.rlock()
+	defer m.runlock()
+
+	if err := m.checkBounds(offset, 8); err != nil {
+		return err
+	}
+	if err := m.checkReadOnly(offset, 8); err != nil {
+		return err
+	}
+
+	err := m.checkFault(func() {
+		binary.LittleEndian.PutUint64(m.data[offset:offset+8], num)
+	})
+	m.markDirty(offset, 8)
+	return err
+}
@@ -0,0 +1,76 @@
+package mmap
+
+import "encoding/binary"
+
+// ReadUvarintAt decodes an unsigned varint from offset and returns
+// the decoded value along with the number of bytes consumed.
+func (m *File) ReadUvarintAt(offset int64) (uint64, int) {
+	m.rlock()
+	defer m.runlock()
+	if m.recoverFaults {
+		defer faultChecks(panicOnFault())
+	}
+
+	return m.uvarint(offset)
+}
+
+// ReadVarintAt decodes a zigzag encoded signed varint from offset and
+// returns the decoded value along with the number of bytes consumed.
+func (m *File) ReadVarintAt(offset int64) (int64, int) {
+	m.rlock()
+	defer m.runlock()
+	if m.recoverFaults {
+		defer faultChecks(panicOnFault())
+	}
+
+	m.boundaryChecks(offset, 1)
+	return checkVarint(binary.Varint(m.data[offset:m.length]))
+}
+
+// WriteUvarintAt encodes num as an unsigned varint at offset and returns the
+// number of bytes written. Encoded num must fit in the mapped region.
+func (m *File) WriteUvarintAt(num uint64, offset int64) int {
+	var buf [binary.MaxVarintLen64]byte
+	return m.writeBytes(binary.AppendUvarint(buf[:0], num), offset)
+}
+
+// WriteVarintAt encodes num as a zigzag encoded signed varint at offset and returns
+// the number of bytes written. Encoded num must fit in the mapped region.
+func (m *File) WriteVarintAt(num int64, offset int64) int {
+	var buf [binary.MaxVarintLen64]byte
+	return m.writeBytes(binary.AppendVarint(buf[:0], num), offset)
+}
+
+// uvarint decodes an unsigned varint from offset. Caller must hold the read lock.
+func (m *File) uvarint(offset int64) (uint64, int) {
+	m.boundaryChecks(offset, 1)
+	return checkVarint(binary.Uvarint(m.data[offset:m.length]))
+}
+
+// checkVarint panics if n returned by encoding/binary indicates that the
+// varint runs past the mapped region or overflows a 64-bit integer.
+func checkVarint[T int64 | uint64](num T, n int) (T, int) {
+	if n == 0 {
+		panic(ErrIndexOutOfBound)
+	} else if n < 0 {
+		panic(ErrVarintOverflow)
+	}
+
+	return num, n
+}
+
+// writeBytes writes all of b at offset and returns len(b).
+func (m *File) writeBytes(b []byte, offset int64) int {
+	m.mustGrow(offset, int64(len(b)))
+
+	m.rlock()
+	defer m.runlock()
+	if m.recoverFaults {
+		defer faultChecks(panicOnFault())
+	}
+
+	m.writeChecks(offset, int64(len(b)))
+	copy(m.data[offset:m.length], b)
+	m.markDirty(offset, int64(len(b)))
+	return len(b)
+}
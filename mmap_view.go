@@ -0,0 +1,52 @@
+package mmap
+
+import "sync"
+
+// View provides zero-copy access to a part of the mapped region. While a View
+// is not released, the mapping cannot be unmapped or resized, functions such
+// as Unmap, Close and Resize fail with ErrMappingInUse instead. Hence, the
+// slice returned by Bytes can be safely accessed until Release is called.
+type View struct {
+	m    *File
+	data []byte
+	once sync.Once
+}
+
+// View returns a View of length bytes of the mapped region starting at given
+// offset. The returned View must be released by calling Release once the
+// mapped memory is no longer accessed through it.
+func (m *File) View(offset, length int64) (*View, error) {
+	m.rlock()
+	defer m.runlock()
+
+	if err := m.checkBounds(offset, length); err != nil {
+		return nil, err
+	}
+
+	m.views.Add(1)
+	return &View{m: m, data: m.data[offset : offset+length : offset+length]}, nil
+}
+
+// Bytes returns the slice referring to the mapped memory of the view.
+// The slice must not be accessed after calling Release. Modifications
+// made through the slice are not tracked by Flush, use FlushRange to
+// flush such modifications to disk. Memory faults raised while accessing
+// the slice, for instance after the backing file is truncated, are not
+// recovered even if the mapping is created using WithFaultRecovery.
+func (v *View) Bytes() []byte {
+	return v.data
+}
+
+// Len returns the number of bytes in the view.
+func (v *View) Len() int {
+	return len(v.data)
+}
+
+// Release releases the view, allowing the mapping to be unmapped or
+// resized once all of its views are released. Release is idempotent.
+func (v *View) Release() {
+	v.once.Do(func() {
+		v.data = nil
+		v.m.views.Add(-1)
+	})
+}
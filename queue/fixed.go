@@ -0,0 +1,180 @@
+package queue
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/grandecola/mmap"
+)
+
+// Layout of a slot, the sequence number of the slot is followed by
+// the length of the message and the message itself.
+const (
+	slotLengthOffset = 8
+	slotDataOffset   = 12
+)
+
+// FixedQueue is a bounded queue of messages stored in a fixed number of slots,
+// each message can be at most slot size bytes long. Every slot carries a
+// sequence number that tells whether the slot is ready to be written or read.
+// FixedQueue is safe for concurrent use by multiple senders and receivers,
+// which may be in different processes.
+//
+// A sender claims a slot before copying the message to it and publishes the
+// message afterwards. If a sender process dies between the two steps, the slot
+// is never published, receivers stop at that slot and the queue eventually
+// fills up; such a queue must be recreated. Processes that die outside of
+// TrySend and TryReceive do not affect the queue and can restart at any time.
+type FixedQueue struct {
+	queue
+	slots    uint64
+	slotSize int64
+	stride   int64
+}
+
+// FixedSize returns the size of the mapped region required
+// by a FixedQueue with given number of slots and slot size.
+func FixedSize(slots, slotSize int) int64 {
+	return headerSize + int64(slots)*fixedStride(slotSize)
+}
+
+// fixedStride returns the size of a slot aligned to 8 bytes.
+func fixedStride(slotSize int) int64 {
+	return (slotDataOffset + int64(slotSize) + 7) &^ 7
+}
+
+// NewFixed creates a FixedQueue in the mapped region m, which must be mapped
+// for reading and writing and must be at least FixedSize bytes long. If m
+// already holds a FixedQueue, the queue must have the same number of slots
+// and slot size, and the messages in it are preserved.
+func NewFixed(m *mmap.File, slots, slotSize int) (*FixedQueue, error) {
+	if slots <= 0 || slotSize <= 0 || slotSize > math.MaxUint32 {
+		return nil, fmt.Errorf("%w: %d slots of size %d", ErrIncompatible, slots, slotSize)
+	}
+
+	q := &FixedQueue{
+		queue:    newQueue(m),
+		slots:    uint64(slots),
+		slotSize: int64(slotSize),
+		stride:   fixedStride(slotSize),
+	}
+	err := q.init(kindFixed, q.slots, uint64(slotSize), FixedSize(slots, slotSize), func() {
+		for i := range q.slots {
+			m.WriteUint64At(i, q.slot(i))
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return q, nil
+}
+
+// OpenFixed opens the FixedQueue stored in the named file, creating the file
+// if it does not exist, as documented for NewFixed. The returned FixedQueue
+// owns the opened file and must be closed using Close.
+func OpenFixed(path string, slots, slotSize int) (*FixedQueue, error) {
+	m, err := openFile(path, FixedSize(max(slots, 0), max(slotSize, 0)))
+	if err != nil {
+		return nil, err
+	}
+
+	q, err := NewFixed(m, slots, slotSize)
+	if err != nil {
+		_ = m.Close()
+		return nil, err
+	}
+
+	q.ownsFile = true
+	return q, nil
+}
+
+// slot returns the offset of the slot for the given position.
+func (q *FixedQueue) slot(pos uint64) int64 {
+	return headerSize + int64(pos%q.slots)*q.stride
+}
+
+// TrySend appends msg to the queue and returns ErrFull if the queue is full.
+func (q *FixedQueue) TrySend(msg []byte) error {
+	if int64(len(msg)) > q.slotSize {
+		return ErrTooLarge
+	}
+
+	for {
+		pos := q.m.AtomicLoadUint64At(tailOffset)
+		slot := q.slot(pos)
+		switch diff := int64(q.m.AtomicLoadUint64At(slot) - pos); {
+		case diff < 0:
+			return ErrFull
+		case diff > 0 || !q.m.CompareAndSwapUint64At(pos, pos+1, tailOffset):
+			continue
+		}
+
+		q.m.WriteUint32At(uint32(len(msg)), slot+slotLengthOffset)
+		if len(msg) > 0 {
+			_, _ = q.m.WriteAt(msg, slot+slotDataOffset)
+		}
+		q.m.AtomicStoreUint64At(pos+1, slot)
+		q.notEmpty.notify()
+		return nil
+	}
+}
+
+// Send appends msg to the queue, waiting while the queue is full. Send returns
+// os.ErrDeadlineExceeded if the queue is still full after timeout elapses.
+// Negative timeout waits indefinitely.
+func (q *FixedQueue) Send(msg []byte, timeout time.Duration) error {
+	return q.wait(q.notFull, timeout, ErrFull, func() error {
+		return q.TrySend(msg)
+	})
+}
+
+// TryReceive removes and returns the message at the front of
+// the queue and returns ErrEmpty if the queue is empty.
+func (q *FixedQueue) TryReceive() ([]byte, error) {
+	for {
+		pos := q.m.AtomicLoadUint64At(headOffset)
+		slot := q.slot(pos)
+		switch diff := int64(q.m.AtomicLoadUint64At(slot) - (pos + 1)); {
+		case diff < 0:
+			return nil, ErrEmpty
+		case diff > 0 || !q.m.CompareAndSwapUint64At(pos, pos+1, headOffset):
+			continue
+		}
+
+		msg := make([]byte, q.m.ReadUint32At(slot+slotLengthOffset))
+		if len(msg) > 0 {
+			_, _ = q.m.ReadAt(msg, slot+slotDataOffset)
+		}
+		q.m.AtomicStoreUint64At(pos+q.slots, slot)
+		q.notFull.notify()
+		return msg, nil
+	}
+}
+
+// Receive removes and returns the message at the front of the queue, waiting
+// while the queue is empty. Receive returns os.ErrDeadlineExceeded if the
+// queue is still empty after timeout elapses. Negative timeout waits indefinitely.
+func (q *FixedQueue) Receive(timeout time.Duration) ([]byte, error) {
+	var msg []byte
+	err := q.wait(q.notEmpty, timeout, ErrEmpty, func() error {
+		var err error
+		msg, err = q.TryReceive()
+		return err
+	})
+
+	return msg, err
+}
+
+// Len returns the number of messages in the queue.
+func (q *FixedQueue) Len() int {
+	head := q.m.AtomicLoadUint64At(headOffset)
+	return int(q.m.AtomicLoadUint64At(tailOffset) - head)
+}
+
+// Close unmaps the queue and closes the file if the queue was
+// opened using OpenFixed. Close does nothing otherwise.
+func (q *FixedQueue) Close() error {
+	return q.close()
+}
@@ -0,0 +1,178 @@
+// Package queue implements message queues stored in a memory mapped file that
+// can be shared by multiple processes. All the state of a queue, including the
+// sequence numbers, lives in the mapped file, hence, either side can restart
+// and continue from where it left off, see FixedQueue for the exception to it.
+// Senders block while a queue is full and receivers block while it is empty,
+// using futex on linux.
+package queue
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/grandecola/mmap"
+)
+
+var (
+	// ErrFull is returned when sending a message to a full queue without waiting.
+	ErrFull = errors.New("queue is full")
+	// ErrEmpty is returned when receiving a message from an empty queue without waiting.
+	ErrEmpty = errors.New("queue is empty")
+	// ErrTooLarge is returned when a message does not fit in a slot or in the queue.
+	ErrTooLarge = errors.New("message too large")
+	// ErrIncompatible is returned when the mapped region holds a different queue.
+	ErrIncompatible = errors.New("incompatible queue")
+)
+
+// Layout of the header of a queue. Head and tail are placed in
+// separate cache lines to avoid false sharing between the sides.
+const (
+	magic        = 0x4555514d // "MQUE"
+	initializing = 1
+
+	magicOffset    = 0
+	kindOffset     = 4
+	capacityOffset = 8
+	slotSizeOffset = 16
+	headOffset     = 64
+	tailOffset     = 128
+
+	notEmptyOffset        = 192
+	notEmptyWaitersOffset = 196
+	notFullOffset         = 200
+	notFullWaitersOffset  = 204
+
+	headerSize = 256
+)
+
+// Kinds of queues.
+const (
+	kindFixed    = 1
+	kindVariable = 2
+)
+
+// initTimeout is the duration to wait for
+// another process to initialize the header.
+const initTimeout = 5 * time.Second
+
+// queue holds the state common to both kinds of queues.
+type queue struct {
+	m        *mmap.File
+	ownsFile bool
+	notEmpty signal
+	notFull  signal
+}
+
+func newQueue(m *mmap.File) queue {
+	return queue{
+		m:        m,
+		notEmpty: signal{m: m, seq: notEmptyOffset, waiters: notEmptyWaitersOffset},
+		notFull:  signal{m: m, seq: notFullOffset, waiters: notFullWaitersOffset},
+	}
+}
+
+// openFile opens or creates the named file of given size
+// and maps it into memory for reading and writing.
+func openFile(path string, size int64) (*mmap.File, error) {
+	return mmap.Open(path, os.O_RDWR|os.O_CREATE, mmap.WithLength(int(size)))
+}
+
+// init initializes the header of a new queue, calling format to initialize
+// the data, or validates the header of an existing queue. If multiple
+// processes open a new queue at once, one of them initializes the header
+// while the others wait for it.
+func (q *queue) init(kind uint32, capacity, slotSize uint64, size int64, format func()) error {
+	if q.m.ValidLength() < size {
+		return fmt.Errorf("%w: mapped length %d, required %d",
+			mmap.ErrIndexOutOfBound, q.m.ValidLength(), size)
+	}
+
+	if q.m.CompareAndSwapUint32At(0, initializing, magicOffset) {
+		q.m.WriteUint32At(kind, kindOffset)
+		q.m.WriteUint64At(capacity, capacityOffset)
+		q.m.WriteUint64At(slotSize, slotSizeOffset)
+		format()
+		q.m.AtomicStoreUint32At(magic, magicOffset)
+		return nil
+	}
+
+	deadline := time.Now().Add(initTimeout)
+	for q.m.AtomicLoadUint32At(magicOffset) == initializing {
+		if time.Now().After(deadline) {
+			return fmt.Errorf("%w: queue not initialized", ErrIncompatible)
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	if q.m.AtomicLoadUint32At(magicOffset) != magic || q.m.ReadUint32At(kindOffset) != kind ||
+		q.m.ReadUint64At(capacityOffset) != capacity || q.m.ReadUint64At(slotSizeOffset) != slotSize {
+		return ErrIncompatible
+	}
+
+	return nil
+}
+
+// wait calls try until it returns an error other than retry, blocking on
+// s between the calls. wait returns os.ErrDeadlineExceeded if try keeps
+// returning retry until timeout elapses. Negative timeout waits indefinitely.
+func (q *queue) wait(s signal, timeout time.Duration, retry error, try func() error) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		seq := s.load()
+		if err := try(); err != retry {
+			return err
+		}
+
+		remaining := time.Until(deadline)
+		if timeout < 0 {
+			remaining = -1
+		} else if remaining <= 0 {
+			return os.ErrDeadlineExceeded
+		}
+
+		if err := s.wait(seq, remaining); err != nil && !errors.Is(err, os.ErrDeadlineExceeded) {
+			return err
+		}
+	}
+}
+
+// close closes the mapped file if it is owned by the queue.
+func (q *queue) close() error {
+	if !q.ownsFile {
+		return nil
+	}
+
+	q.ownsFile = false
+	return q.m.Close()
+}
+
+// signal is a sequence number in the mapped region which is incremented on
+// every notification, waiters block until the sequence number changes. The
+// number of waiters is tracked to avoid the wake syscall when none is waiting.
+type signal struct {
+	m       *mmap.File
+	seq     int64
+	waiters int64
+}
+
+func (s signal) load() uint32 {
+	return s.m.AtomicLoadUint32At(s.seq)
+}
+
+// wait blocks until the sequence number is different from seq.
+func (s signal) wait(seq uint32, timeout time.Duration) error {
+	s.m.AtomicAddUint32At(1, s.waiters)
+	defer s.m.AtomicAddUint32At(^uint32(0), s.waiters)
+
+	return s.m.WaitUint32At(seq, s.seq, timeout)
+}
+
+// notify wakes up all the waiters.
+func (s signal) notify() {
+	s.m.AtomicAddUint32At(1, s.seq)
+	if s.m.AtomicLoadUint32At(s.waiters) > 0 {
+		_, _ = s.m.WakeUint32At(s.seq, 1<<31-1)
+	}
+}
@@ -0,0 +1,229 @@
+package queue
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"sync"
+	"testing"
+	"time"
+)
+
+// sendReceive sends count messages from one mapping of the queue and receives
+// them from another mapping of the same file, as if they were two processes.
+func sendReceive(t *testing.T, sender, receiver interface {
+	Send([]byte, time.Duration) error
+	Receive(time.Duration) ([]byte, error)
+}, count int) {
+	t.Helper()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := range count {
+			if err := sender.Send([]byte(fmt.Sprintf("message %d", i)), -1); err != nil {
+				t.Errorf("error in sending message :: %v", err)
+				return
+			}
+		}
+	}()
+
+	for i := range count {
+		msg, err := receiver.Receive(5 * time.Second)
+		if err != nil {
+			t.Fatalf("error in receiving message :: %v", err)
+		}
+		if string(msg) != fmt.Sprintf("message %d", i) {
+			t.Fatalf("message received %q is not equal to message %d sent", msg, i)
+		}
+	}
+	wg.Wait()
+}
+
+func TestFixedQueue(t *testing.T) {
+	t.Parallel()
+
+	testPath := path.Join(t.TempDir(), "m.queue")
+	q, err := OpenFixed(testPath, 4, 16)
+	if err != nil {
+		t.Fatalf("error in opening queue :: %v", err)
+	}
+
+	if _, err := q.TryReceive(); err != ErrEmpty {
+		t.Fatalf("different error than expected in TryReceive :: %v", err)
+	}
+	if err := q.TrySend(make([]byte, 17)); err != ErrTooLarge {
+		t.Fatalf("different error than expected in TrySend :: %v", err)
+	}
+	for i := range 4 {
+		if err := q.TrySend(bytes.Repeat([]byte{'a'}, i*5)); err != nil {
+			t.Fatalf("error in sending message :: %v", err)
+		}
+	}
+	if err := q.TrySend(nil); err != ErrFull {
+		t.Fatalf("different error than expected in TrySend :: %v", err)
+	}
+	if err := q.Send(nil, 10*time.Millisecond); !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Fatalf("different error than expected in Send :: %v", err)
+	}
+	if msg, err := q.TryReceive(); err != nil || len(msg) != 0 {
+		t.Fatalf("error in receiving message :: %v", err)
+	}
+	if err := q.Close(); err != nil {
+		t.Fatalf("error in closing queue :: %v", err)
+	}
+
+	// Reopen the queue, messages are preserved
+	if _, err := OpenFixed(testPath, 4, 32); err != ErrIncompatible {
+		t.Fatalf("different error than expected in OpenFixed :: %v", err)
+	}
+	q, err = OpenFixed(testPath, 4, 16)
+	if err != nil {
+		t.Fatalf("error in opening queue :: %v", err)
+	}
+	if q.Len() != 3 {
+		t.Fatalf("length of queue %d, expected 3", q.Len())
+	}
+	for i := 1; i < 4; i++ {
+		if msg, err := q.Receive(0); err != nil || !bytes.Equal(msg, bytes.Repeat([]byte{'a'}, i*5)) {
+			t.Fatalf("message received is not equal to message sent :: %v", err)
+		}
+	}
+	if _, err := q.Receive(10 * time.Millisecond); !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Fatalf("different error than expected in Receive :: %v", err)
+	}
+
+	other, err := OpenFixed(testPath, 4, 16)
+	if err != nil {
+		t.Fatalf("error in opening queue :: %v", err)
+	}
+	sendReceive(t, q, other, 1000)
+
+	if err := other.Close(); err != nil {
+		t.Fatalf("error in closing queue :: %v", err)
+	}
+	if err := q.Close(); err != nil {
+		t.Fatalf("error in closing queue :: %v", err)
+	}
+}
+
+func TestFixedQueueRestart(t *testing.T) {
+	t.Parallel()
+
+	testPath := path.Join(t.TempDir(), "m.queue")
+	receiver, err := OpenFixed(testPath, 8, 16)
+	if err != nil {
+		t.Fatalf("error in opening queue :: %v", err)
+	}
+
+	// Sender restarts between messages, receiver keeps receiving in order
+	for i := range 3 {
+		sender, err := OpenFixed(testPath, 8, 16)
+		if err != nil {
+			t.Fatalf("error in opening queue :: %v", err)
+		}
+		for j := range 2 {
+			if err := sender.TrySend([]byte(fmt.Sprintf("message %d", i*2+j))); err != nil {
+				t.Fatalf("error in sending message :: %v", err)
+			}
+		}
+		if err := sender.Close(); err != nil {
+			t.Fatalf("error in closing queue :: %v", err)
+		}
+	}
+
+	for i := range 6 {
+		if i == 3 {
+			// Receiver restarts as well
+			if err := receiver.Close(); err != nil {
+				t.Fatalf("error in closing queue :: %v", err)
+			}
+			if receiver, err = OpenFixed(testPath, 8, 16); err != nil {
+				t.Fatalf("error in opening queue :: %v", err)
+			}
+		}
+
+		msg, err := receiver.TryReceive()
+		if err != nil || string(msg) != fmt.Sprintf("message %d", i) {
+			t.Fatalf("message received %q is not equal to message %d sent :: %v", msg, i, err)
+		}
+	}
+	if _, err := receiver.TryReceive(); err != ErrEmpty {
+		t.Fatalf("different error than expected in TryReceive :: %v", err)
+	}
+	if err := receiver.Close(); err != nil {
+		t.Fatalf("error in closing queue :: %v", err)
+	}
+}
+
+func TestVariableQueue(t *testing.T) {
+	t.Parallel()
+
+	testPath := path.Join(t.TempDir(), "m.queue")
+	if _, err := OpenVariable(testPath, 100); !errors.Is(err, ErrIncompatible) {
+		t.Fatalf("different error than expected in OpenVariable :: %v", err)
+	}
+
+	q, err := OpenVariable(testPath, 128)
+	if err != nil {
+		t.Fatalf("error in opening queue :: %v", err)
+	}
+
+	if _, err := q.TryReceive(); err != ErrEmpty {
+		t.Fatalf("different error than expected in TryReceive :: %v", err)
+	}
+	if err := q.TrySend(make([]byte, 57)); err != ErrTooLarge {
+		t.Fatalf("different error than expected in TrySend :: %v", err)
+	}
+
+	// Messages of different lengths wrap around the end of the data region
+	for i := range 100 {
+		msg := bytes.Repeat([]byte{byte(i)}, i%57)
+		if err := q.TrySend(msg); err != nil {
+			t.Fatalf("error in sending message :: %v", err)
+		}
+		if received, err := q.TryReceive(); err != nil || !bytes.Equal(received, msg) {
+			t.Fatalf("message received is not equal to message sent :: %v", err)
+		}
+	}
+
+	for q.TrySend([]byte("0123456789")) == nil {
+	}
+	if err := q.Send([]byte("0123456789"), 10*time.Millisecond); !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Fatalf("different error than expected in Send :: %v", err)
+	}
+	length := q.Len()
+	if err := q.Close(); err != nil {
+		t.Fatalf("error in closing queue :: %v", err)
+	}
+
+	// Reopen the queue, messages are preserved
+	q, err = OpenVariable(testPath, 128)
+	if err != nil {
+		t.Fatalf("error in opening queue :: %v", err)
+	}
+	if q.Len() != length {
+		t.Fatalf("length of queue %d, expected %d", q.Len(), length)
+	}
+	for q.Len() > 0 {
+		if msg, err := q.Receive(0); err != nil || string(msg) != "0123456789" {
+			t.Fatalf("message received is not equal to message sent :: %v", err)
+		}
+	}
+
+	other, err := OpenVariable(testPath, 128)
+	if err != nil {
+		t.Fatalf("error in opening queue :: %v", err)
+	}
+	sendReceive(t, q, other, 1000)
+
+	if err := other.Close(); err != nil {
+		t.Fatalf("error in closing queue :: %v", err)
+	}
+	if err := q.Close(); err != nil {
+		t.Fatalf("error in closing queue :: %v", err)
+	}
+}
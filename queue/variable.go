@@ -0,0 +1,172 @@
+package queue
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/grandecola/mmap"
+)
+
+// Layout of a record in VariableQueue, the length of the message is followed
+// by 4 reserved bytes and the message itself. Records are aligned to 8 bytes.
+// A record that does not fit before the end of the data region is preceded
+// by padding until the end, which is marked by the padding length.
+const (
+	recordHeaderSize = 8
+	paddingLength    = math.MaxUint32
+)
+
+// VariableQueue is a bounded queue of variable length messages stored back to
+// back in a data region of fixed capacity. A message can be at most half of the
+// capacity long, including 8 bytes of framing. VariableQueue is safe for use by
+// a single sender and a single receiver, which may be in different processes.
+type VariableQueue struct {
+	queue
+	capacity uint64
+}
+
+// VariableSize returns the size of the mapped region required
+// by a VariableQueue with data region of given capacity.
+func VariableSize(capacity int) int64 {
+	return headerSize + int64(capacity)
+}
+
+// NewVariable creates a VariableQueue in the mapped region m, which must be
+// mapped for reading and writing and must be at least VariableSize bytes long.
+// capacity must be a multiple of 8. If m already holds a VariableQueue, the
+// queue must have the same capacity, and the messages in it are preserved.
+func NewVariable(m *mmap.File, capacity int) (*VariableQueue, error) {
+	if capacity < 2*recordHeaderSize || capacity%8 != 0 {
+		return nil, fmt.Errorf("%w: capacity %d", ErrIncompatible, capacity)
+	}
+
+	q := &VariableQueue{queue: newQueue(m), capacity: uint64(capacity)}
+	if err := q.init(kindVariable, q.capacity, 0, VariableSize(capacity), func() {}); err != nil {
+		return nil, err
+	}
+
+	return q, nil
+}
+
+// OpenVariable opens the VariableQueue stored in the named file, creating the
+// file if it does not exist, as documented for NewVariable. The returned
+// VariableQueue owns the opened file and must be closed using Close.
+func OpenVariable(path string, capacity int) (*VariableQueue, error) {
+	m, err := openFile(path, VariableSize(max(capacity, 0)))
+	if err != nil {
+		return nil, err
+	}
+
+	q, err := NewVariable(m, capacity)
+	if err != nil {
+		_ = m.Close()
+		return nil, err
+	}
+
+	q.ownsFile = true
+	return q, nil
+}
+
+// recordSize returns the size of the record of a message of given length.
+func recordSize(length int) uint64 {
+	return (recordHeaderSize + uint64(length) + 7) &^ 7
+}
+
+// TrySend appends msg to the queue and returns ErrFull if
+// the queue does not have enough free space for msg.
+func (q *VariableQueue) TrySend(msg []byte) error {
+	size := recordSize(len(msg))
+	if size > q.capacity/2 {
+		return ErrTooLarge
+	}
+
+	tail := q.m.AtomicLoadUint64At(tailOffset)
+	head := q.m.AtomicLoadUint64At(headOffset)
+	var padding uint64
+	if pos := tail % q.capacity; q.capacity-pos < size {
+		padding = q.capacity - pos
+	}
+	if tail+padding+size-head > q.capacity {
+		return ErrFull
+	}
+
+	if padding > 0 {
+		q.m.WriteUint32At(paddingLength, q.offset(tail))
+		tail += padding
+	}
+	q.m.WriteUint32At(uint32(len(msg)), q.offset(tail))
+	if len(msg) > 0 {
+		_, _ = q.m.WriteAt(msg, q.offset(tail)+recordHeaderSize)
+	}
+
+	q.m.AtomicStoreUint64At(tail+size, tailOffset)
+	q.notEmpty.notify()
+	return nil
+}
+
+// Send appends msg to the queue, waiting while the queue does not have enough
+// free space for msg. Send returns os.ErrDeadlineExceeded if the queue still
+// does not have enough free space after timeout elapses. Negative timeout
+// waits indefinitely.
+func (q *VariableQueue) Send(msg []byte, timeout time.Duration) error {
+	return q.wait(q.notFull, timeout, ErrFull, func() error {
+		return q.TrySend(msg)
+	})
+}
+
+// TryReceive removes and returns the message at the front of
+// the queue and returns ErrEmpty if the queue is empty.
+func (q *VariableQueue) TryReceive() ([]byte, error) {
+	head := q.m.AtomicLoadUint64At(headOffset)
+	if head == q.m.AtomicLoadUint64At(tailOffset) {
+		return nil, ErrEmpty
+	}
+
+	length := q.m.ReadUint32At(q.offset(head))
+	if length == paddingLength {
+		head += q.capacity - head%q.capacity
+		length = q.m.ReadUint32At(q.offset(head))
+	}
+
+	msg := make([]byte, length)
+	if length > 0 {
+		_, _ = q.m.ReadAt(msg, q.offset(head)+recordHeaderSize)
+	}
+
+	q.m.AtomicStoreUint64At(head+recordSize(len(msg)), headOffset)
+	q.notFull.notify()
+	return msg, nil
+}
+
+// Receive removes and returns the message at the front of the queue, waiting
+// while the queue is empty. Receive returns os.ErrDeadlineExceeded if the
+// queue is still empty after timeout elapses. Negative timeout waits indefinitely.
+func (q *VariableQueue) Receive(timeout time.Duration) ([]byte, error) {
+	var msg []byte
+	err := q.wait(q.notEmpty, timeout, ErrEmpty, func() error {
+		var err error
+		msg, err = q.TryReceive()
+		return err
+	})
+
+	return msg, err
+}
+
+// Len returns the number of bytes used by the messages in
+// the queue, including the framing and the padding.
+func (q *VariableQueue) Len() int {
+	head := q.m.AtomicLoadUint64At(headOffset)
+	return int(q.m.AtomicLoadUint64At(tailOffset) - head)
+}
+
+// offset returns the offset in the mapped region for the given position.
+func (q *VariableQueue) offset(pos uint64) int64 {
+	return headerSize + int64(pos%q.capacity)
+}
+
+// Close unmaps the queue and closes the file if the queue was
+// opened using OpenVariable. Close does nothing otherwise.
+func (q *VariableQueue) Close() error {
+	return q.close()
+}